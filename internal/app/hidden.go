@@ -0,0 +1,36 @@
+package app
+
+import (
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/pattern"
+	"github.com/nathan-fiscaletti/git-switch/internal/storage"
+	"github.com/samber/lo"
+)
+
+// HiddenBranches returns the names of the branches that shouldn't be listed,
+// either because of the include and exclude patterns in the config and the
+// repository's config, or because the user hid them. The current branch is
+// never hidden.
+func HiddenBranches(cfg *storage.Config, repository *storage.RepositoryConfig, branches []git.Branch, currentBranch string) ([]string, error) {
+	include, err := pattern.Compile(append(append([]string{}, cfg.Include...), repository.Include...))
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := pattern.Compile(append(append([]string{}, cfg.Exclude...), repository.Exclude...))
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.FilterMap(branches, func(b git.Branch, _ int) (string, bool) {
+		if b.Name == currentBranch {
+			return "", false
+		}
+
+		hidden := (len(include) > 0 && !include.Match(b.Name)) ||
+			exclude.Match(b.Name) ||
+			lo.Contains(repository.HiddenBranches, b.Name)
+
+		return b.Name, hidden
+	}), nil
+}
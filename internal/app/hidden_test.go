@@ -0,0 +1,62 @@
+package app
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/storage"
+	"github.com/samber/lo"
+)
+
+func TestHiddenBranches(t *testing.T) {
+	branches := lo.Map([]string{
+		"main",
+		"feature/login",
+		"dependabot/npm/lodash",
+		"renovate/go",
+		"release/2019",
+		"release/2024",
+		"experiment",
+	}, func(name string, _ int) git.Branch {
+		return git.Branch{Name: name, Local: true}
+	})
+
+	cfg := &storage.Config{
+		Exclude: []string{"dependabot/*", "renovate/*"},
+	}
+	repository := &storage.RepositoryConfig{
+		Exclude:        []string{"re:release/201\\d"},
+		HiddenBranches: []string{"experiment"},
+	}
+
+	got, err := HiddenBranches(cfg, repository, branches, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"dependabot/npm/lodash", "renovate/go", "release/2019", "experiment"}
+	if !slices.Equal(got, want) {
+		t.Errorf("hidden branches = %v, want %v", got, want)
+	}
+
+	// With include patterns only matching branches are listed, but the
+	// current branch always is.
+	cfg = &storage.Config{Include: []string{"release/*"}}
+	got, err = HiddenBranches(cfg, &storage.RepositoryConfig{}, branches, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{"feature/login", "dependabot/npm/lodash", "renovate/go", "experiment"}
+	if !slices.Equal(got, want) {
+		t.Errorf("hidden branches = %v, want %v", got, want)
+	}
+}
+
+func TestHiddenBranchesInvalidPattern(t *testing.T) {
+	cfg := &storage.Config{Exclude: []string{"re:("}}
+	if _, err := HiddenBranches(cfg, &storage.RepositoryConfig{}, nil, "main"); err == nil {
+		t.Error("HiddenBranches with an invalid pattern succeeded")
+	}
+}
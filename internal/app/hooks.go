@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// runHook runs the hook command with the shell in the repository at dir,
+// telling it which branch or commit is switched from and to. Nothing is run
+// if command is empty.
+func runHook(ctx context.Context, name, command, dir, from, to string) error {
+	if command == "" {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_SWITCH_FROM="+from, "GIT_SWITCH_TO="+to)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v hook: %w", name, err)
+	}
+
+	return nil
+}
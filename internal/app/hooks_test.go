@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/git/gittest"
+	"github.com/nathan-fiscaletti/git-switch/internal/storage"
+)
+
+// useHooks sets the hooks in the user's config.
+func useHooks(t *testing.T, preSwitch, postSwitch string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are written for sh")
+	}
+
+	cfg := "hooks:\n  pre-switch: '" + preSwitch + "'\n  post-switch: '" + postSwitch + "'\n"
+	if err := os.WriteFile(filepath.Join(storage.Directory, "config"), []byte(cfg), 0o660); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSwitchRunsHooks(t *testing.T) {
+	useTempDirectory(t)
+	useHooks(t,
+		`echo "pre $GIT_SWITCH_FROM $GIT_SWITCH_TO" >> hooks.log`,
+		`echo "post $GIT_SWITCH_FROM $GIT_SWITCH_TO" >> hooks.log`,
+	)
+	backend := gittest.New(t.TempDir(), "main", "feature")
+
+	checkout(t, backend, "feature")
+	// Hooks aren't run when staying on the same branch.
+	checkout(t, backend, "feature")
+
+	log, err := os.ReadFile(filepath.Join(backend.RepositoryPath, "hooks.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "pre main feature\npost main feature\n"; string(log) != want {
+		t.Errorf("hooks ran %q, want %q", log, want)
+	}
+}
+
+func TestFailingPreSwitchHookCancelsSwitch(t *testing.T) {
+	useTempDirectory(t)
+	useHooks(t, "exit 1", "")
+	backend := gittest.New(t.TempDir(), "main", "feature")
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: git.Branch{Name: "feature", Ref: "refs/heads/feature", Local: true},
+	})
+	if err == nil {
+		t.Fatal("Switch succeeded with a failing pre-switch hook")
+	}
+
+	if backend.CurrentBranch != "main" {
+		t.Errorf("on %q, want to still be on main", backend.CurrentBranch)
+	}
+}
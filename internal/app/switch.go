@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nathan-fiscaletti/git-switch/internal"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/storage"
+	"github.com/samber/lo"
+)
+
+var (
+	ErrNoHistory = errors.New("no branch to pop to")
+	ErrNoPins    = errors.New("no pinned branches")
+)
+
+// BranchHistory returns the branches visited before current, most recent
+// first. current is what is checked out, see HistoryEntry. When enabled, the
+// stored history is reconciled with the reflog so that it's correct even when
+// branches were switched using git directly. Branches that no longer exist
+// are left out. Detached commits are listed by their full hash.
+func BranchHistory(ctx context.Context, backend git.Backend, cfg *storage.Config, repository *storage.RepositoryConfig, current string, branches []git.Branch) ([]string, error) {
+	history := repository.BranchHistory()
+	if cfg.ReflogHistory {
+		checkouts, err := backend.ListCheckouts(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		history = storage.MergeHistory(history, git.VisitedBranches(checkouts), cfg.HistoryDepth)
+	}
+
+	return lo.Filter(history, func(branch string, _ int) bool {
+		return branch != current && (git.IsFullHash(branch) || lo.ContainsBy(branches, func(b git.Branch) bool {
+			return b.Name == branch
+		}))
+	}), nil
+}
+
+// HistoryEntry returns how head is recorded in the branch history: the
+// branch that is checked out, or the full hash of the commit HEAD is detached
+// at so that it can be returned to.
+func HistoryEntry(head git.Head) string {
+	if head.Detached() {
+		return head.Hash
+	}
+
+	return head.Branch
+}
+
+// Pop checks out the branch visited depth branches ago according to history,
+// 1 being the last one, and returns its name. Detached commits are checked
+// out detached again.
+func Pop(ctx context.Context, backend git.Backend, history []string, depth int) (string, error) {
+	if len(history) == 0 {
+		return "", ErrNoHistory
+	}
+
+	if depth < 1 || depth > len(history) {
+		return "", fmt.Errorf("only %v branches in history", len(history))
+	}
+
+	branch := history[depth-1]
+	if git.IsFullHash(branch) {
+		return branch, Switch(ctx, backend, internal.Selection{
+			Branch: git.Branch{Name: branch, Ref: branch, Hash: branch},
+			Detach: true,
+		})
+	}
+
+	return branch, Switch(ctx, backend, internal.Selection{
+		Branch: git.Branch{Name: branch, Ref: "refs/heads/" + branch, Local: true},
+	})
+}
+
+// Go checks out the pinned branch numbered n, 1 being the first, as numbered
+// in the selector, and returns its name.
+func Go(ctx context.Context, backend git.Backend, pins []string, branches []git.Branch, n int) (string, error) {
+	names := lo.Map(branches, func(b git.Branch, _ int) string {
+		return b.Name
+	})
+
+	pinned := internal.PinnedBranches(pins, names)
+	if len(pinned) == 0 {
+		return "", ErrNoPins
+	}
+
+	if n < 1 || n > len(pinned) {
+		return "", fmt.Errorf("only %v pinned branches", len(pinned))
+	}
+
+	branch, _ := lo.Find(branches, func(b git.Branch) bool {
+		return b.Name == pinned[n-1]
+	})
+
+	selection := internal.Selection{Branch: branch}
+	if branch.IsRemoteOnly() {
+		if len(branch.Remotes) > 1 {
+			return "", fmt.Errorf("%v exists on more than one remote, select it in the selector instead", branch.Name)
+		}
+		selection.Remote = branch.Remotes[0]
+	}
+
+	return branch.Name, Switch(ctx, backend, selection)
+}
+
+// Switch checks out the selected branch, first creating it if it was
+// selected to be created, and records the branch that was checked out before
+// in the branch history. Branches that only exist on a remote get a local
+// branch tracking the remote that was picked. Tags and commits are checked
+// out with HEAD detached when the selection asks for it.
+//
+// Uncommitted changes are stashed first if the selection asks for it. With
+// auto-stash turned on for the repository, they are stashed for the branch
+// being left, and the changes stashed for the selected branch are restored.
+//
+// The pre-switch and post-switch hooks of the repository are run before and
+// after switching to another branch.
+func Switch(ctx context.Context, backend git.Backend, selection internal.Selection) error {
+	head, err := backend.GetHead(ctx)
+	if err != nil {
+		return err
+	}
+
+	repositoryPath, err := backend.GetRepositoryPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	currentBranch := head.Branch
+	branch := selection.Branch.Name
+	switching := branch != currentBranch
+
+	var hooks storage.Hooks
+	if switching {
+		repositoryCfg, err := storage.GetConfig(repositoryPath)
+		if err != nil {
+			return err
+		}
+		hooks = repositoryCfg.Hooks
+	}
+
+	err = runHook(ctx, "pre-switch", hooks.PreSwitch, repositoryPath, HistoryEntry(head), branch)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := storage.SetLastBranch(ctx, backend, HistoryEntry(head))
+	if err != nil {
+		return err
+	}
+
+	repository, err := cfg.GetRepositoryConfig(repositoryPath)
+	if err != nil {
+		return err
+	}
+
+	// Nothing needs stashing when staying on the same branch. New branches
+	// start from where the changes were made, so the changes are carried
+	// over to them. There is no branch to stash changes for when HEAD is
+	// detached.
+	autoStash := repository.AutoStash && switching && !selection.Create && currentBranch != ""
+
+	var stash string
+	if (selection.Stash && switching) || autoStash {
+		stash, err = backend.StashPush(ctx, fmt.Sprintf("git-switch: changes on %v", currentBranch))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = checkoutSelection(ctx, backend, selection)
+	if err != nil {
+		// Put the changes back so they aren't left in a stash the user
+		// doesn't know about.
+		if stash != "" {
+			if popErr := backend.StashPop(ctx, stash); popErr != nil {
+				return errors.Join(err, fmt.Errorf("restoring stashed changes: %w", popErr))
+			}
+		}
+		return err
+	}
+
+	if autoStash {
+		if stash != "" {
+			if _, err := storage.SetStash(ctx, backend, currentBranch, stash); err != nil {
+				return err
+			}
+		}
+
+		// Changes are only stashed for branches, which a tag may share its
+		// name with.
+		if !selection.Detach {
+			if err := restoreStash(ctx, backend, repository, branch); err != nil {
+				return err
+			}
+		}
+	}
+
+	return runHook(ctx, "post-switch", hooks.PostSwitch, repositoryPath, HistoryEntry(head), branch)
+}
+
+// checkoutSelection checks out the selected branch, creating it first if
+// needed, or the selected tag or commit detached.
+func checkoutSelection(ctx context.Context, backend git.Backend, selection internal.Selection) error {
+	branch := selection.Branch.Name
+
+	if selection.Detach {
+		return backend.CheckoutDetached(ctx, selection.Branch.Ref)
+	}
+
+	if selection.Create {
+		err := backend.CreateBranch(ctx, branch, selection.Base)
+		if err != nil {
+			return err
+		}
+	}
+
+	if selection.Branch.IsRemoteOnly() && selection.Remote != "" {
+		return backend.CheckoutTracking(ctx, selection.Remote, branch)
+	}
+
+	return backend.Checkout(ctx, branch)
+}
+
+// restoreStash restores the changes auto-stashed when switching away from
+// branch, if any.
+func restoreStash(ctx context.Context, backend git.Backend, repository *storage.RepositoryConfig, branch string) error {
+	stash, ok := repository.Stashes[branch]
+	if !ok {
+		return nil
+	}
+
+	// A stash that was dropped by hand can't be restored, so it's forgotten
+	// too.
+	err := backend.StashPop(ctx, stash)
+	if err != nil && !errors.Is(err, git.ErrStashNotFound) {
+		return fmt.Errorf("restoring the changes stashed on %v: %w", branch, err)
+	}
+
+	_, err = storage.SetStash(ctx, backend, branch, "")
+	return err
+}
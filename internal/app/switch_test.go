@@ -0,0 +1,435 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/git/gittest"
+	"github.com/nathan-fiscaletti/git-switch/internal/storage"
+	"github.com/samber/lo"
+)
+
+// useTempDirectory stores the config in a temporary directory for the
+// duration of the test.
+func useTempDirectory(t *testing.T) {
+	t.Helper()
+
+	previous := storage.Directory
+	storage.Directory = t.TempDir()
+	t.Cleanup(func() { storage.Directory = previous })
+}
+
+// history returns the branch history of the repository backend is for.
+func history(t *testing.T, backend *gittest.Backend) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg, err := storage.GetConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository, err := cfg.GetRepositoryConfig(backend.RepositoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err := backend.ListBranches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := backend.GetHead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := BranchHistory(ctx, backend, cfg, repository, HistoryEntry(head), branches)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return history
+}
+
+func checkout(t *testing.T, backend *gittest.Backend, branch string) {
+	t.Helper()
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: git.Branch{Name: branch, Ref: "refs/heads/" + branch, Local: true},
+	})
+	if err != nil {
+		t.Fatalf("Switch(%q): %v", branch, err)
+	}
+}
+
+func TestSwitchRecordsHistory(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main", "a", "b")
+
+	checkout(t, backend, "a")
+	checkout(t, backend, "b")
+
+	if backend.CurrentBranch != "b" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "b")
+	}
+
+	want := []string{"a", "main"}
+	if got := history(t, backend); !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestSwitchTracksRemoteBranch(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main")
+	backend.AddRemoteBranch("origin", "feature")
+	backend.AddRemoteBranch("upstream", "feature")
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: backend.Branches[1],
+		Remote: "upstream",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feature := backend.Branches[1]
+	if !feature.Local || feature.Upstream != "upstream/feature" {
+		t.Errorf("feature = %+v, want a local branch tracking upstream/feature", feature)
+	}
+
+	if backend.CurrentBranch != "feature" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "feature")
+	}
+}
+
+func TestSwitchCreatesBranch(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main", "develop")
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: git.Branch{Name: "feature", Local: true},
+		Create: true,
+		Base:   "refs/heads/develop",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "feature" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "feature")
+	}
+}
+
+func TestSwitchFailsForMissingBranch(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main")
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: git.Branch{Name: "missing", Local: true},
+	})
+
+	var gitErr *git.Error
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("Switch to a missing branch = %v, want a *git.Error", err)
+	}
+
+	if backend.CurrentBranch != "main" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "main")
+	}
+}
+
+func TestPop(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "a", "b")
+
+	checkout(t, backend, "a")
+	checkout(t, backend, "b")
+
+	branch, err := Pop(ctx, backend, history(t, backend), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if branch != "a" || backend.CurrentBranch != "a" {
+		t.Errorf("popped to %q with %q checked out, want %q", branch, backend.CurrentBranch, "a")
+	}
+
+	// Popping again goes back to where we popped from.
+	if _, err := Pop(ctx, backend, history(t, backend), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "b" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "b")
+	}
+}
+
+func TestPopDepth(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "a", "b")
+
+	checkout(t, backend, "a")
+	checkout(t, backend, "b")
+
+	if _, err := Pop(ctx, backend, history(t, backend), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "main" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "main")
+	}
+
+	if _, err := Pop(ctx, backend, history(t, backend), 3); err == nil {
+		t.Error("Pop past the end of the history succeeded")
+	}
+}
+
+func TestPopWithoutHistory(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main")
+
+	if _, err := Pop(context.Background(), backend, history(t, backend), 1); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("Pop = %v, want %v", err, ErrNoHistory)
+	}
+}
+
+func TestBranchHistoryUsesReflog(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main", "a", "b", "deleted")
+
+	// Branches switched using git directly are only in the reflog.
+	backend.CurrentBranch = "main"
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	backend.Checkouts = []git.CheckoutEntry{
+		{From: "b", To: "main"},
+		{From: commit, To: "b"},
+		{From: "deleted", To: commit},
+		{From: "a", To: "deleted"},
+	}
+	backend.Branches = backend.Branches[:3]
+
+	want := []string{"b", commit, "a"}
+	if got := history(t, backend); !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestGo(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "release/2.0", "release/1.0", "feature")
+	backend.AddRemoteBranch("origin", "release/3.0")
+	backend.AddRemoteBranch("origin", "main")
+
+	pins := []string{"feature", "release/*"}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "feature"},
+		{3, "release/2.0"},
+		{4, "release/3.0"},
+	}
+
+	for _, tt := range tests {
+		branches, err := backend.ListBranches(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Go(ctx, backend, pins, branches, tt.n)
+		if err != nil {
+			t.Fatalf("Go(%v): %v", tt.n, err)
+		}
+
+		if got != tt.want || backend.CurrentBranch != tt.want {
+			t.Errorf("Go(%v) checked out %q and returned %q, want %q", tt.n, backend.CurrentBranch, got, tt.want)
+		}
+	}
+
+	if branch, _ := lo.Find(backend.Branches, func(b git.Branch) bool { return b.Name == "release/3.0" }); branch.Upstream != "origin/release/3.0" {
+		t.Errorf("release/3.0 tracks %q, want origin/release/3.0", branch.Upstream)
+	}
+
+	if _, err := Go(ctx, backend, pins, backend.Branches, 5); err == nil {
+		t.Error("Go(5) with 4 pinned branches succeeded")
+	}
+
+	if _, err := Go(ctx, backend, nil, backend.Branches, 1); !errors.Is(err, ErrNoPins) {
+		t.Errorf("Go without pins = %v, want %v", err, ErrNoPins)
+	}
+}
+
+func TestSwitchStashes(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main", "feature")
+	backend.LocalChanges = "work on main"
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: backend.Branches[1],
+		Stash:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "feature" || backend.LocalChanges != "" {
+		t.Errorf("on %q with changes %q, want feature without changes", backend.CurrentBranch, backend.LocalChanges)
+	}
+
+	if len(backend.Stashes) != 1 || backend.Stashes[0].Message != "git-switch: changes on main" {
+		t.Errorf("stashes = %+v, want the changes on main", backend.Stashes)
+	}
+}
+
+func TestSwitchAutoStash(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "feature")
+
+	if _, err := storage.SetAutoStash(ctx, backend, true); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.LocalChanges = "work on main"
+	checkout(t, backend, "feature")
+
+	if backend.LocalChanges != "" {
+		t.Errorf("changes %q were carried to feature", backend.LocalChanges)
+	}
+
+	backend.LocalChanges = "work on feature"
+	checkout(t, backend, "main")
+
+	if backend.LocalChanges != "work on main" {
+		t.Errorf("changes on main = %q after switching back, want %q", backend.LocalChanges, "work on main")
+	}
+
+	checkout(t, backend, "feature")
+
+	if backend.LocalChanges != "work on feature" {
+		t.Errorf("changes on feature = %q after switching back, want %q", backend.LocalChanges, "work on feature")
+	}
+
+	if len(backend.Stashes) != 1 {
+		t.Errorf("stashes = %+v, want only the changes on main", backend.Stashes)
+	}
+
+	// Stashes dropped by hand are forgotten.
+	backend.Stashes = nil
+	checkout(t, backend, "main")
+
+	cfg, err := storage.GetConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository, err := cfg.GetRepositoryConfig("/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"feature": backend.Stashes[0].Hash}; !maps.Equal(repository.Stashes, want) {
+		t.Errorf("recorded stashes = %v, want %v", repository.Stashes, want)
+	}
+}
+
+func TestSwitchRestoresStashWhenCheckoutFails(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main")
+	backend.LocalChanges = "work on main"
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: git.Branch{Name: "missing", Local: true},
+		Stash:  true,
+	})
+	if err == nil {
+		t.Fatal("checking out a missing branch succeeded")
+	}
+
+	if backend.LocalChanges != "work on main" || len(backend.Stashes) != 0 {
+		t.Errorf("changes = %q and stashes = %+v, want the changes restored", backend.LocalChanges, backend.Stashes)
+	}
+}
+
+func TestSwitchToCurrentBranchKeepsChanges(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main", "feature")
+
+	if _, err := storage.SetAutoStash(context.Background(), backend, true); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.LocalChanges = "work on main"
+	checkout(t, backend, "main")
+
+	if backend.LocalChanges != "work on main" || len(backend.Stashes) != 0 {
+		t.Errorf("changes = %q and stashes = %+v, want the changes left alone", backend.LocalChanges, backend.Stashes)
+	}
+}
+
+func TestSwitchToTag(t *testing.T) {
+	useTempDirectory(t)
+	backend := gittest.New("/repo", "main")
+	backend.Tags = []git.Branch{{Name: "v1.0", Ref: "refs/tags/v1.0", Hash: "0123456789abcdef0123456789abcdef01234567"}}
+
+	err := Switch(context.Background(), backend, internal.Selection{
+		Branch: backend.Tags[0],
+		Detach: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "" || backend.Detached != backend.Tags[0].Hash {
+		t.Errorf("on %q detached at %q, want detached at v1.0", backend.CurrentBranch, backend.Detached)
+	}
+
+	if want := []string{"main"}; !slices.Equal(history(t, backend), want) {
+		t.Errorf("history = %v, want %v", history(t, backend), want)
+	}
+}
+
+func TestPopToDetachedCommit(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "feature")
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	backend.CurrentBranch = ""
+	backend.Detached = commit
+
+	checkout(t, backend, "main")
+	checkout(t, backend, "feature")
+
+	want := []string{"main", commit}
+	if got := history(t, backend); !slices.Equal(got, want) {
+		t.Fatalf("history = %v, want %v", got, want)
+	}
+
+	if _, err := Pop(ctx, backend, history(t, backend), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "" || backend.Detached != commit {
+		t.Errorf("on %q detached at %q, want detached at %q", backend.CurrentBranch, backend.Detached, commit)
+	}
+
+	// The branch left is recorded, so popping returns to it.
+	if _, err := Pop(ctx, backend, history(t, backend), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if backend.CurrentBranch != "feature" {
+		t.Errorf("current branch = %q, want %q", backend.CurrentBranch, "feature")
+	}
+}
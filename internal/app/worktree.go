@@ -0,0 +1,43 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+// OtherWorktrees returns the branches checked out in worktrees other than the
+// one at repositoryPath, mapped to the path of the worktree they are checked
+// out in.
+func OtherWorktrees(worktrees []git.Worktree, repositoryPath string) map[string]string {
+	current := resolvePath(repositoryPath)
+
+	result := map[string]string{}
+	for _, worktree := range worktrees {
+		if worktree.Branch == "" || resolvePath(worktree.Path) == current {
+			continue
+		}
+
+		result[worktree.Branch] = worktree.Path
+	}
+
+	return result
+}
+
+// WorktreePath returns the path to create a worktree for branch at, which is
+// next to the repository at repositoryPath and named after both.
+func WorktreePath(repositoryPath, branch string) string {
+	name := filepath.Base(repositoryPath) + "-" + strings.ReplaceAll(branch, "/", "-")
+	return filepath.Join(filepath.Dir(repositoryPath), name)
+}
+
+// resolvePath returns path with symbolic links resolved, or path itself if it
+// can't be resolved, so that paths git reports can be compared.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+
+	return filepath.Clean(path)
+}
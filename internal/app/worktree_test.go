@@ -0,0 +1,42 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+func TestOtherWorktrees(t *testing.T) {
+	dir := t.TempDir()
+	repository := filepath.Join(dir, "repo")
+	if err := os.Mkdir(repository, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current worktree is recognized through a symbolic link too.
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(repository, link); err != nil {
+		t.Skipf("can't create symbolic links: %v", err)
+	}
+
+	worktrees := []git.Worktree{
+		{Path: link, Branch: "main"},
+		{Path: "/src/repo-feature", Branch: "feature"},
+		{Path: "/src/repo-detached", Detached: true},
+	}
+
+	want := map[string]string{"feature": "/src/repo-feature"}
+	if got := OtherWorktrees(worktrees, repository); !reflect.DeepEqual(got, want) {
+		t.Errorf("OtherWorktrees = %v, want %v", got, want)
+	}
+}
+
+func TestWorktreePath(t *testing.T) {
+	got := WorktreePath(filepath.FromSlash("/src/repo"), "feature/login")
+	if want := filepath.FromSlash("/src/repo-feature-login"); got != want {
+		t.Errorf("WorktreePath = %q, want %q", got, want)
+	}
+}
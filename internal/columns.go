@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// Columns that can be displayed next to each branch name.
+const (
+	ColumnDate     = "date"
+	ColumnAuthor   = "author"
+	ColumnHash     = "hash"
+	ColumnUpstream = "upstream"
+	ColumnRemotes  = "remotes"
+	ColumnSubject  = "subject"
+
+	// ColumnAheadBehind shows how far a branch is ahead of and behind its
+	// upstream.
+	ColumnAheadBehind = "ahead-behind"
+	// ColumnDivergence shows how far a branch is ahead of and behind the
+	// current branch.
+	ColumnDivergence = "divergence"
+)
+
+// columnName is the branch name itself. It is always displayed first and
+// can't be configured.
+const columnName = "name"
+
+// Columns lists every supported column.
+var Columns = []string{
+	ColumnDate,
+	ColumnAuthor,
+	ColumnHash,
+	ColumnUpstream,
+	ColumnRemotes,
+	ColumnSubject,
+	ColumnAheadBehind,
+	ColumnDivergence,
+}
+
+// asyncColumns are columns whose values are loaded in the background, and
+// only for the branches that are visible. They always have a fixed width.
+var asyncColumns = []string{
+	ColumnAheadBehind,
+	ColumnDivergence,
+}
+
+// maxColumnWidth is the widest a column is allowed to grow before its values
+// are truncated. Columns not listed here are never padded or truncated, and
+// only make sense as the last column.
+var maxColumnWidth = map[string]int{
+	columnName:     48,
+	ColumnDate:     16,
+	ColumnAuthor:   20,
+	ColumnHash:     7,
+	ColumnUpstream: 32,
+	ColumnRemotes:  24,
+
+	ColumnAheadBehind: 12,
+	ColumnDivergence:  12,
+}
+
+// columnValue returns the text to display in column for branch b.
+func columnValue(column string, b git.Branch, now time.Time) string {
+	switch column {
+	case columnName:
+		return b.Name
+	case ColumnDate:
+		if b.CommitterDate.IsZero() {
+			return ""
+		}
+		return relativeTime(b.CommitterDate, now)
+	case ColumnAuthor:
+		return b.Author
+	case ColumnHash:
+		if len(b.Hash) > 7 {
+			return b.Hash[:7]
+		}
+		return b.Hash
+	case ColumnUpstream:
+		return b.Upstream
+	case ColumnRemotes:
+		return strings.Join(b.Remotes, ", ")
+	case ColumnSubject:
+		return b.Subject
+	}
+
+	return ""
+}
+
+// aheadBehindKey identifies a pair of revisions to compare.
+type aheadBehindKey struct {
+	ref  string
+	base string
+}
+
+// columnValue returns the text to display in column for branch b. Values for
+// async columns are loaded in the background, with a placeholder returned
+// until they are ready.
+func (r *Renderer) columnValue(column string, b git.Branch, now time.Time) string {
+	var base string
+	switch column {
+	case ColumnAheadBehind:
+		base = b.Upstream
+	case ColumnDivergence:
+		if !b.Local || b.Name != r.cfg.CurrentBranch {
+			base = r.cfg.CurrentBranch
+		}
+	default:
+		return columnValue(column, b, now)
+	}
+
+	if base == "" || r.cfg.AheadBehind == nil {
+		return ""
+	}
+
+	key := aheadBehindKey{ref: b.Ref, base: base}
+	result, ok := r.aheadBehind.get(key, func() (git.AheadBehind, error) {
+		return r.cfg.AheadBehind(key.ref, key.base)
+	})
+
+	switch {
+	case !ok:
+		return "…"
+	case result.err != nil:
+		return "?"
+	}
+
+	return fmt.Sprintf("↑%v ↓%v", result.value.Ahead, result.value.Behind)
+}
+
+// upstreamCounts returns how far b is ahead of and behind its upstream,
+// loading the counts in the background if needed. It returns false until
+// they have loaded, and for branches without an upstream.
+func (r *Renderer) upstreamCounts(b git.Branch) (git.AheadBehind, bool) {
+	if b.Upstream == "" || r.cfg.AheadBehind == nil {
+		return git.AheadBehind{}, false
+	}
+
+	key := aheadBehindKey{ref: b.Ref, base: b.Upstream}
+	result, ok := r.aheadBehind.get(key, func() (git.AheadBehind, error) {
+		return r.cfg.AheadBehind(key.ref, key.base)
+	})
+
+	return result.value, ok && result.err == nil
+}
+
+// columnWidth returns the width needed to display column for every branch,
+// limited to the column's maximum width.
+func columnWidth(column string, branches []git.Branch, now time.Time) int {
+	if lo.Contains(asyncColumns, column) {
+		return maxColumnWidth[column]
+	}
+
+	width := lo.Max(lo.Map(branches, func(b git.Branch, _ int) int {
+		return runewidth.StringWidth(columnValue(column, b, now))
+	}))
+
+	if limit, ok := maxColumnWidth[column]; ok {
+		return min(width, limit)
+	}
+
+	return width
+}
+
+// fitText pads or truncates s so that it takes exactly width cells on screen,
+// wide runes such as CJK characters taking two. A width of zero or less
+// returns s unchanged.
+func fitText(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	if runewidth.StringWidth(s) > width {
+		s = runewidth.Truncate(s, width, "…")
+	}
+
+	return runewidth.FillRight(s, width)
+}
+
+// relativeTime formats t as a human friendly duration relative to now, such
+// as "3 days ago".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %v ago", unit)
+		}
+		return fmt.Sprintf("%v %vs ago", n, unit)
+	}
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	case d < 7*24*time.Hour:
+		return plural(int(d.Hours()/24), "day")
+	case d < 30*24*time.Hour:
+		return plural(int(d.Hours()/24/7), "week")
+	case d < 365*24*time.Hour:
+		return plural(int(d.Hours()/24/30), "month")
+	}
+
+	return plural(int(d.Hours()/24/365), "year")
+}
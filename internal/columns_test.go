@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		// Commits dated in the future, such as from a skewed clock.
+		{-time.Hour, "just now"},
+		{time.Minute, "1 minute ago"},
+		{59 * time.Minute, "59 minutes ago"},
+		{time.Hour, "1 hour ago"},
+		{23 * time.Hour, "23 hours ago"},
+		{24 * time.Hour, "1 day ago"},
+		{6 * 24 * time.Hour, "6 days ago"},
+		{7 * 24 * time.Hour, "1 week ago"},
+		{29 * 24 * time.Hour, "4 weeks ago"},
+		{30 * 24 * time.Hour, "1 month ago"},
+		{364 * 24 * time.Hour, "12 months ago"},
+		{365 * 24 * time.Hour, "1 year ago"},
+		{3 * 365 * 24 * time.Hour, "3 years ago"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("relativeTime(%v ago) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
+
+func TestFitText(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"main", 6, "main  "},
+		{"main", 4, "main"},
+		{"feature", 4, "fea…"},
+		{"feature", 1, "…"},
+		{"", 3, "   "},
+		// Zero or negative widths leave the text alone.
+		{"main", 0, "main"},
+		{"main", -1, "main"},
+		// Wide runes take two cells.
+		{"修正", 6, "修正  "},
+		{"修正", 4, "修正"},
+		{"修正する", 5, "修正…"},
+		// A wide rune that doesn't fit is replaced by padding.
+		{"修正する", 6, "修正… "},
+		{"ñandú", 6, "ñandú "},
+	}
+
+	for _, tt := range tests {
+		if got := fitText(tt.s, tt.width); got != tt.want {
+			t.Errorf("fitText(%q, %v) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestColumnWidth(t *testing.T) {
+	now := time.Now()
+	branches := []git.Branch{
+		{Name: "main", Author: "Ada Lovelace", Hash: "0123456789abcdef0123456789abcdef01234567"},
+		{Name: "修正/ログイン", Author: "A very long author name indeed", Subject: "Fix the login form"},
+	}
+
+	tests := []struct {
+		column   string
+		branches []git.Branch
+		want     int
+	}{
+		// Wide runes count for two cells.
+		{columnName, branches, 13},
+		// Values are limited to the column's maximum width.
+		{ColumnAuthor, branches, 20},
+		{ColumnHash, branches, 7},
+		// Columns without a maximum width take their widest value.
+		{ColumnSubject, branches, 18},
+		// Empty values take no room.
+		{ColumnUpstream, branches, 0},
+		{ColumnSubject, nil, 0},
+		// Columns loaded in the background always have their maximum
+		// width, since their values aren't known yet.
+		{ColumnAheadBehind, nil, 12},
+		{ColumnDivergence, branches, 12},
+	}
+
+	for _, tt := range tests {
+		if got := columnWidth(tt.column, tt.branches, now); got != tt.want {
+			t.Errorf("columnWidth(%q) = %v, want %v", tt.column, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// commitColumns are the columns displayed next to each commit, which is
+// named after its abbreviated hash.
+var commitColumns = []string{ColumnDate, ColumnAuthor, ColumnSubject}
+
+// commitLog holds the commits listed after drilling down into a branch, along
+// with the state of the list to go back to.
+type commitLog struct {
+	// branch is the branch or tag the commits are on.
+	branch git.Branch
+	// commits are the commits on branch, most recent first. They are nil
+	// until they have loaded.
+	commits []git.Branch
+	// matched holds the commits matching matchedInput, in the order of
+	// commits. Only they can match a search starting with matchedInput.
+	matched      []git.Branch
+	matchedInput string
+
+	mode        string
+	input       string
+	selected    int
+	windowStart int
+}
+
+// commitKeys are the keys that do nothing while commits are listed, in
+// addition to branchKeys.
+var commitKeys = []tcell.Key{
+	tcell.KeyCtrlO,
+	tcell.KeyCtrlR,
+	tcell.KeyCtrlG,
+	tcell.KeyCtrlP,
+}
+
+// listingBranches reports whether the list is made of branches, rather than
+// tags or commits.
+func (r *Renderer) listingBranches() bool {
+	return r.state.Mode == modeBranches || r.state.Mode == modeHistory
+}
+
+// columns returns the columns displayed next to each item in the list.
+func (r *Renderer) columns() []string {
+	if r.state.Mode == modeCommits {
+		return commitColumns
+	}
+
+	return r.cfg.Columns
+}
+
+// browseCommits lists the commits on the named branch or tag in place of the
+// list, which is restored by leaveCommits. The commits are loaded in the
+// background, see loadCommits.
+func (r *Renderer) browseCommits(name string) {
+	branch, _ := r.branch(name)
+
+	r.state.Log = &commitLog{
+		branch:      branch,
+		mode:        r.state.Mode,
+		input:       r.state.Input,
+		selected:    r.state.Selected,
+		windowStart: r.state.WindowStart,
+	}
+	r.state.Mode = modeCommits
+	r.state.Input = ""
+	r.state.Selected = 0
+	r.state.WindowStart = 0
+	r.state.Branches = nil
+
+	r.loadCommits()
+}
+
+// loadCommits lists the commits being browsed once they have loaded, or goes
+// back to the previous list if they can't be loaded.
+func (r *Renderer) loadCommits() {
+	log := r.state.Log
+	if log == nil || log.commits != nil {
+		return
+	}
+
+	result, ok := r.commits.get(log.branch.Ref, func() ([]git.Branch, error) {
+		return r.cfg.Commits(log.branch)
+	})
+	if !ok {
+		return
+	}
+
+	if result.err != nil {
+		r.leaveCommits()
+		r.state.Branches = r.filter(r.state.Input)
+		r.state.Message = result.err.Error()
+		return
+	}
+
+	log.commits = result.value
+	if log.commits == nil {
+		log.commits = []git.Branch{}
+	}
+	r.refreshBranchListWithSelection("", false)
+}
+
+// leaveCommits goes back to the list the commits were browsed from.
+func (r *Renderer) leaveCommits() {
+	log := r.state.Log
+	r.state.Log = nil
+	r.state.Mode = log.mode
+	r.state.Input = log.input
+	r.state.Selected = log.selected
+	r.state.WindowStart = log.windowStart
+}
+
+// handleCommitsKey handles the keys that behave differently while commits
+// are listed, returning false for the keys handled like in any other list.
+func (r *Renderer) handleCommitsKey(handler SelectionHandler, ev *tcell.EventKey) bool {
+	switch {
+	case ev.Key() == tcell.KeyEsc || ev.Key() == tcell.KeyCtrlL:
+		r.leaveCommits()
+		r.state.Branches = r.filter(r.state.Input)
+	case ev.Key() == tcell.KeyEnter:
+		if selectedCommit, ok := r.selectedBranch(); ok {
+			r.selectCommit(handler, selectedCommit)
+		}
+	case lo.Contains(branchKeys, ev.Key()) || lo.Contains(commitKeys, ev.Key()):
+	default:
+		return false
+	}
+
+	return true
+}
+
+// selectCommit asks whether to check out the named commit detached, or to
+// create a branch at it.
+func (r *Renderer) selectCommit(handler SelectionHandler, name string) {
+	commit, _ := r.branch(name)
+
+	options := []promptOption{
+		{key: 'd', label: "detached", run: func() error {
+			r.selectDetached(handler, name)
+			return nil
+		}},
+	}
+
+	if r.cfg.AllowCreate {
+		options = append(options, promptOption{key: 'b', label: "new branch", run: func() error {
+			r.createBranchAtCommit(handler, commit)
+			return nil
+		}})
+	}
+
+	r.openPrompt(fmt.Sprintf("Check out %v?", name), options...)
+}
+
+// createBranchAtCommit asks for the name of a branch to create at commit.
+func (r *Renderer) createBranchAtCommit(handler SelectionHandler, commit git.Branch) {
+	r.openInputPrompt(fmt.Sprintf("Create a branch at %v named", commit.Name), func(text string) error {
+		name := strings.TrimSpace(text)
+		if name == "" {
+			r.state.Message = "no branch name given"
+			return nil
+		}
+
+		if lo.ContainsBy(r.cfg.Branches, func(b git.Branch) bool { return b.Name == name }) {
+			r.state.Message = fmt.Sprintf("a branch named %v already exists", name)
+			return nil
+		}
+
+		r.confirmOperation(name, func() {
+			r.createBranchAt(handler, name, commit.Hash)
+		})
+		return nil
+	})
+}
+
+// rank returns the names of the commits matching input, best match first.
+// When input extends the previous search, only the commits that matched it
+// are ranked again, so that typing a search doesn't match every commit on
+// each key press.
+func (l *commitLog) rank(input string) []string {
+	if l.commits == nil {
+		return nil
+	}
+
+	candidates := l.commits
+	if l.matched != nil && strings.HasPrefix(input, l.matchedInput) {
+		candidates = l.matched
+	}
+
+	names := rankCommits(input, candidates)
+
+	matched := lo.Keyify(names)
+	l.matched = lo.Filter(candidates, func(c git.Branch, _ int) bool {
+		return lo.HasKey(matched, c.Name)
+	})
+	l.matchedInput = input
+
+	return names
+}
+
+// rankCommits returns the names of the commits whose abbreviated hash,
+// subject or author fuzzy match input, best match first. Commits are listed
+// in the order given while input is empty.
+func rankCommits(input string, commits []git.Branch) []string {
+	names := lo.Map(commits, func(c git.Branch, _ int) string {
+		return c.Name
+	})
+	byName := lo.KeyBy(commits, func(c git.Branch) string {
+		return c.Name
+	})
+
+	return rankBy(input, names, func(name string) []string {
+		commit := byName[name]
+		return []string{commit.Name, commit.Subject, commit.Author}
+	})
+}
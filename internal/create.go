@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// createBranchRow is placed in the branch list in place of a branch name to
+// represent the row offering to create a branch named after the search
+// input. It contains a NUL byte so that it can never be a real branch name.
+const createBranchRow = "\x00create"
+
+// selectedBranch returns the name of the highlighted branch. It returns false
+// when the list is empty or the create branch row is highlighted.
+func (r *Renderer) selectedBranch() (string, bool) {
+	if len(r.state.Branches) == 0 || r.isCreateRowSelected() {
+		return "", false
+	}
+
+	return r.state.Branches[r.state.Selected], true
+}
+
+// isCreateRowSelected reports whether the create branch row is highlighted.
+func (r *Renderer) isCreateRowSelected() bool {
+	return len(r.state.Branches) > 0 && r.state.Branches[r.state.Selected] == createBranchRow
+}
+
+// canCreateBranch reports whether a branch with the given name can be
+// created, which is only the case when no branch already has that name.
+// Branches can't be created while tags or commits are listed.
+func (r *Renderer) canCreateBranch(name string) bool {
+	if !r.cfg.AllowCreate || name == "" || !r.listingBranches() {
+		return false
+	}
+
+	_, exists := r.branch(name)
+	return !exists
+}
+
+// createBranch creates a branch named after the search input starting from
+// base, or from HEAD if base is empty. If there are remotes to push to, the
+// user is first asked whether to push the new branch.
+func (r *Renderer) createBranch(handler SelectionHandler, base string) {
+	r.confirmOperation(r.state.Input, func() {
+		r.createBranchAnyway(handler, base)
+	})
+}
+
+// createBranchAnyway creates a branch like createBranch, without warning
+// about an operation in progress.
+func (r *Renderer) createBranchAnyway(handler SelectionHandler, base string) {
+	var ref string
+	if base != "" {
+		// Start from the ref rather than the name so that branches that only
+		// exist on a remote can be used as a base too.
+		branch, _ := r.branch(base)
+		ref = branch.Ref
+	}
+
+	r.createBranchAt(handler, r.state.Input, ref)
+}
+
+// createBranchAt selects a branch called name to be created starting from
+// ref, or from HEAD if ref is empty. If there are remotes to push to, the
+// user is first asked whether to push the new branch.
+func (r *Renderer) createBranchAt(handler SelectionHandler, name, ref string) {
+	selection := Selection{
+		Branch: git.Branch{Name: name, Local: true},
+		Create: true,
+		Base:   ref,
+	}
+
+	finish := func(remote string) error {
+		selection.PushRemote = remote
+		r.state.Quit = true
+		if handler.OnSelect != nil {
+			handler.OnSelect(selection)
+		}
+		return nil
+	}
+
+	if len(r.cfg.Remotes) == 0 {
+		finish("")
+		return
+	}
+
+	no := promptOption{key: 'n', label: "no", run: func() error {
+		return finish("")
+	}}
+
+	if len(r.cfg.Remotes) == 1 {
+		remote := r.cfg.Remotes[0]
+		r.openPrompt(fmt.Sprintf("Push %v to %v and set it as upstream?", name, remote),
+			promptOption{key: 'y', label: "yes", run: func() error {
+				return finish(remote)
+			}},
+			no,
+		)
+		return
+	}
+
+	options := lo.Map(r.cfg.Remotes[:min(len(r.cfg.Remotes), 9)], func(remote string, idx int) promptOption {
+		return promptOption{
+			key:   rune('1' + idx),
+			label: remote,
+			run: func() error {
+				return finish(remote)
+			},
+		}
+	})
+
+	r.openPrompt(fmt.Sprintf("Push %v and set it as upstream?", name), append(options, no)...)
+}
+
+// createRowLabel returns the text displayed for the create branch row.
+func (r *Renderer) createRowLabel() string {
+	return fmt.Sprintf("+ create branch %v from HEAD", r.state.Input)
+}
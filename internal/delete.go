@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// deleteBranch asks the user to confirm deleting the named branch and then
+// deletes it. Local branches are deleted first, after which the user is
+// offered to delete the branch from its remotes as well.
+func (r *Renderer) deleteBranch(handler SelectionHandler, name string) {
+	branch, _ := r.branch(name)
+
+	if branch.Local && branch.Name == r.cfg.CurrentBranch {
+		r.state.Message = fmt.Sprintf("can't delete %v, it is checked out", name)
+		return
+	}
+
+	if !branch.Local {
+		r.promptDeleteRemoteBranch(handler, branch)
+		return
+	}
+
+	if handler.OnDelete == nil {
+		return
+	}
+
+	var remove func(force bool) error
+	remove = func(force bool) error {
+		err := handler.OnDelete(name, force)
+		if errors.Is(err, git.ErrBranchNotMerged) && !force {
+			r.openPrompt(fmt.Sprintf("%v is not fully merged. Delete it anyway?", name),
+				promptOption{key: 'y', label: "yes", run: func() error {
+					return remove(true)
+				}},
+			)
+			return nil
+		}
+		if err != nil {
+			r.state.Message = fmt.Sprintf("error: %v", err)
+			return nil
+		}
+
+		r.afterDeleteBranch(handler, name)
+		return nil
+	}
+
+	r.openPrompt(fmt.Sprintf("Delete branch %v?", name),
+		promptOption{key: 'y', label: "yes", run: func() error {
+			return remove(false)
+		}},
+	)
+}
+
+// afterDeleteBranch updates the list after the local branch has been deleted
+// and offers to delete the branch from its remotes.
+func (r *Renderer) afterDeleteBranch(handler SelectionHandler, name string) {
+	r.updateBranch(name, func(b *git.Branch) {
+		b.Local = false
+		b.Upstream = ""
+		if len(b.Remotes) > 0 {
+			b.Ref = fmt.Sprintf("refs/remotes/%v/%v", b.Remotes[0], b.Name)
+		}
+	})
+
+	r.state.Message = fmt.Sprintf("deleted %v", name)
+
+	if branch, found := r.branch(name); found {
+		r.promptDeleteRemoteBranch(handler, branch)
+	}
+}
+
+// promptDeleteRemoteBranch offers to delete branch from one of its remotes.
+func (r *Renderer) promptDeleteRemoteBranch(handler SelectionHandler, branch git.Branch) {
+	if handler.OnDeleteRemote == nil || len(branch.Remotes) == 0 {
+		return
+	}
+
+	remove := func(remote string) error {
+		if err := handler.OnDeleteRemote(remote, branch.Name); err != nil {
+			r.state.Message = fmt.Sprintf("error: %v", err)
+			return nil
+		}
+
+		r.updateBranch(branch.Name, func(b *git.Branch) {
+			b.Remotes = lo.Without(b.Remotes, remote)
+			if !b.Local && len(b.Remotes) > 0 {
+				b.Ref = fmt.Sprintf("refs/remotes/%v/%v", b.Remotes[0], b.Name)
+			}
+		})
+
+		r.state.Message = fmt.Sprintf("deleted %v from %v", branch.Name, remote)
+		return nil
+	}
+
+	if len(branch.Remotes) == 1 {
+		remote := branch.Remotes[0]
+		r.openPrompt(fmt.Sprintf("Delete %v from %v too?", branch.Name, remote),
+			promptOption{key: 'y', label: "yes", run: func() error {
+				return remove(remote)
+			}},
+		)
+		return
+	}
+
+	options := lo.Map(branch.Remotes[:min(len(branch.Remotes), 9)], func(remote string, idx int) promptOption {
+		return promptOption{
+			key:   rune('1' + idx),
+			label: remote,
+			run: func() error {
+				return remove(remote)
+			},
+		}
+	})
+
+	r.openPrompt(fmt.Sprintf("Delete %v from which remote?", branch.Name), options...)
+}
+
+// updateBranch applies update to the named branch, removing the branch from
+// the list if it no longer exists anywhere, and then refreshes the list.
+func (r *Renderer) updateBranch(name string, update func(b *git.Branch)) {
+	_, idx, found := lo.FindIndexOf(r.cfg.Branches, func(b git.Branch) bool {
+		return b.Name == name
+	})
+	if !found {
+		return
+	}
+
+	branches := make([]git.Branch, 0, len(r.cfg.Branches))
+	branches = append(branches, r.cfg.Branches...)
+	update(&branches[idx])
+
+	if !branches[idx].Local && len(branches[idx].Remotes) == 0 {
+		branches = append(branches[:idx], branches[idx+1:]...)
+	}
+
+	r.cfg.Branches = branches
+
+	// Stay in position, selecting the next branch if this one is gone.
+	var nextBranch string
+	if _, stillExists := r.branch(name); stillExists {
+		nextBranch = name
+	} else if r.state.Selected+1 < len(r.state.Branches) {
+		nextBranch = r.state.Branches[r.state.Selected+1]
+	} else if r.state.Selected > 0 {
+		nextBranch = r.state.Branches[r.state.Selected-1]
+	}
+
+	r.refreshBranchListWithSelection(nextBranch, false)
+}
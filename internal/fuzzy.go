@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"math"
+	"sync"
+	"unicode"
+)
+
+// Scoring weights used by fuzzyMatch. A match is always worth scoreMatch,
+// with bonuses added when the matched rune sits on a word boundary and
+// penalties subtracted for every rune skipped between two matches.
+const (
+	scoreMatch          = 16
+	scoreGapPenalty     = 1
+	bonusPrefix         = 12
+	bonusBoundary       = 10
+	bonusCamelCase      = 8
+	bonusConsecutive    = 6
+	bonusFirstCharacter = 2
+)
+
+// fuzzyBuffers holds the slices fuzzyMatch works with, so that ranking a
+// long list doesn't allocate them again for every name.
+type fuzzyBuffers struct {
+	pattern, text, lowerText []rune
+	// prev and cur hold the scores of two consecutive rows of the table,
+	// see fuzzyMatch.
+	prev, cur []int
+	// from holds where the previous pattern rune landed for each score in
+	// the table, one row per pattern rune.
+	from []int
+}
+
+var fuzzyPool = sync.Pool{
+	New: func() any { return &fuzzyBuffers{} },
+}
+
+// fuzzyMatch reports whether every rune of pattern appears in s in order
+// (case-insensitively). When it does, it also returns a score where higher
+// is a better match, and the rune indices in s that were matched.
+func fuzzyMatch(pattern, s string) (int, []int, bool) {
+	buf := fuzzyPool.Get().(*fuzzyBuffers)
+	defer fuzzyPool.Put(buf)
+
+	lp := buf.pattern[:0]
+	for _, ch := range pattern {
+		lp = append(lp, unicode.ToLower(ch))
+	}
+	t, lt := buf.text[:0], buf.lowerText[:0]
+	for _, ch := range s {
+		t = append(t, ch)
+		lt = append(lt, unicode.ToLower(ch))
+	}
+	buf.pattern, buf.text, buf.lowerText = lp, t, lt
+
+	if len(lp) == 0 {
+		return 0, nil, true
+	}
+
+	if len(lp) > len(lt) {
+		return 0, nil, false
+	}
+
+	// Quick rejection: make sure the pattern is a subsequence at all before
+	// doing any of the scoring work.
+	j := 0
+	for i := 0; i < len(lt) && j < len(lp); i++ {
+		if lt[i] == lp[j] {
+			j++
+		}
+	}
+	if j < len(lp) {
+		return 0, nil, false
+	}
+
+	// Row i of the table holds, for each j, the best score for matching
+	// lp[:i+1] with lp[i] landing on lt[j], or none when that isn't
+	// possible. Only the previous row is needed to work out the next one.
+	// from[i*len(lt)+j] holds the position lp[i-1] landed on for that best
+	// score. Scores can be negative when many runes are skipped.
+	const none = math.MinInt
+	prev, cur := grow(buf.prev, len(lt)), grow(buf.cur, len(lt))
+	from := grow(buf.from, len(lp)*len(lt))
+	buf.prev, buf.cur, buf.from = prev, cur, from
+
+	for i := range lp {
+		row := from[i*len(lt) : (i+1)*len(lt)]
+
+		// Matching lp[i] after skipping runes costs a point for each rune
+		// skipped, so the best earlier position k to come from is the one
+		// with the highest prev[k] + k. It is kept up to date as j moves
+		// right, preferring the earliest position on ties.
+		gapBest, gapFrom := none, -1
+
+		for j := range lt {
+			cur[j], row[j] = none, -1
+
+			if i > 0 && j >= 2 && prev[j-2] != none && prev[j-2]+j-2 > gapBest {
+				gapBest, gapFrom = prev[j-2]+j-2, j-2
+			}
+
+			if j < i || lt[j] != lp[i] {
+				continue
+			}
+
+			bonus := runeBonus(t, j)
+
+			if i == 0 {
+				cur[j] = scoreMatch + bonus*bonusFirstCharacter
+				continue
+			}
+
+			if gapFrom != -1 {
+				cur[j] = gapBest - (j - 1) + scoreMatch + bonus
+				row[j] = gapFrom
+			}
+
+			if prev[j-1] != none {
+				if s := prev[j-1] + scoreMatch + bonus + bonusConsecutive; s > cur[j] {
+					cur[j] = s
+					row[j] = j - 1
+				}
+			}
+		}
+
+		prev, cur = cur, prev
+	}
+
+	// Pick the best final position and walk back through the table to
+	// recover which runes were matched.
+	last := len(lp) - 1
+	best, bestAt := none, -1
+	for j := range lt {
+		if prev[j] > best {
+			best = prev[j]
+			bestAt = j
+		}
+	}
+
+	if bestAt == -1 {
+		return 0, nil, false
+	}
+
+	positions := make([]int, len(lp))
+	for i, at := last, bestAt; i >= 0; i-- {
+		positions[i] = at
+		at = from[i*len(lt)+at]
+	}
+
+	return best, positions, true
+}
+
+// grow returns s resized to n, reusing its backing array when it is large
+// enough.
+func grow(s []int, n int) []int {
+	if cap(s) < n {
+		return make([]int, n)
+	}
+	return s[:n]
+}
+
+// runeBonus returns the bonus for matching the rune at index i of s based on
+// what precedes it.
+func runeBonus(s []rune, i int) int {
+	if i == 0 {
+		return bonusPrefix
+	}
+
+	prev, cur := s[i-1], s[i]
+	switch {
+	case prev == '/' || prev == '-' || prev == '_' || prev == '.' || unicode.IsSpace(prev):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamelCase
+	case !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return bonusCamelCase
+	}
+
+	return 0
+}
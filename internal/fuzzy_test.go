@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		score      int
+		positions  []int
+		ok         bool
+	}{
+		{pattern: "", s: "main", ok: true},
+		{pattern: "ab", s: "ab", score: 62, positions: []int{0, 1}, ok: true},
+		{pattern: "AB", s: "ab", score: 62, positions: []int{0, 1}, ok: true},
+		{pattern: "b", s: "ab", score: 16, positions: []int{1}, ok: true},
+		// Skipped runes cost a point each, which a word boundary makes up for.
+		{pattern: "ab", s: "a-b", score: 65, positions: []int{0, 2}, ok: true},
+		{pattern: "fb", s: "fooBar", score: 62, positions: []int{0, 3}, ok: true},
+		{pattern: "fb", s: "foo/bar", score: 63, positions: []int{0, 4}, ok: true},
+		// The best match is kept over the first one.
+		{pattern: "b", s: "abc/b", score: 36, positions: []int{4}, ok: true},
+		{pattern: "fnapi", s: "feature/new-api", score: 126, positions: []int{0, 8, 12, 13, 14}, ok: true},
+		// Long gaps can make the score negative, but still match.
+		{pattern: "ab", s: "a" + strings.Repeat("x", 80) + "b", score: -24, positions: []int{0, 81}, ok: true},
+		{pattern: "ba", s: "ab"},
+		{pattern: "abc", s: "ab"},
+	}
+
+	for _, tt := range tests {
+		score, positions, ok := fuzzyMatch(tt.pattern, tt.s)
+		if score != tt.score || !slices.Equal(positions, tt.positions) || ok != tt.ok {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v, %v, want %v, %v, %v",
+				tt.pattern, tt.s, score, positions, ok, tt.score, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestRankBranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		branches []string
+		want     []string
+	}{
+		{
+			name:     "no input",
+			input:    "",
+			branches: []string{"main", "feature", "develop"},
+			want:     []string{"main", "feature", "develop"},
+		},
+		{
+			name:     "best score first",
+			input:    "fnapi",
+			branches: []string{"main", "fun-api", "feature/new-api"},
+			want:     []string{"feature/new-api", "fun-api"},
+		},
+		{
+			name:     "word boundary first",
+			input:    "b",
+			branches: []string{"abc", "x/b"},
+			want:     []string{"x/b", "abc"},
+		},
+		{
+			name:     "exact match before longer names",
+			input:    "main",
+			branches: []string{"main-old", "main"},
+			want:     []string{"main", "main-old"},
+		},
+		{
+			name:     "ties keep their order",
+			input:    "a",
+			branches: []string{"ca", "ba"},
+			want:     []string{"ca", "ba"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rankBranches(tt.input, tt.branches); !slices.Equal(got, tt.want) {
+				t.Errorf("rankBranches(%q, %v) = %v, want %v", tt.input, tt.branches, got, tt.want)
+			}
+		})
+	}
+}
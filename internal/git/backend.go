@@ -0,0 +1,159 @@
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backend is how git-switch reads and changes the branches of a repository.
+// ExecBackend, which runs the git executable, is used by default.
+type Backend interface {
+	// GetRepositoryPath returns the path to the root of the work tree.
+	GetRepositoryPath(ctx context.Context) (string, error)
+	// GetCurrentBranch returns the name of the branch that is checked out.
+	GetCurrentBranch(ctx context.Context) (string, error)
+	// GetHead returns what is checked out, describing the commit HEAD is
+	// detached at when no branch is checked out.
+	GetHead(ctx context.Context) (Head, error)
+	// ListBranches returns the local and remote branches of the repository.
+	ListBranches(ctx context.Context) ([]Branch, error)
+	// ListTags returns the tags of the repository, described like branches
+	// by the commit they point at.
+	ListTags(ctx context.Context) ([]Branch, error)
+	// ListRemotes returns the names of the remotes of the repository.
+	ListRemotes(ctx context.Context) ([]string, error)
+	// ListCheckouts returns every checkout recorded in the HEAD reflog, most
+	// recent first.
+	ListCheckouts(ctx context.Context) ([]CheckoutEntry, error)
+	// ListWorktrees returns the worktrees of the repository, the main
+	// worktree first.
+	ListWorktrees(ctx context.Context) ([]Worktree, error)
+	// Checkout checks out a local branch.
+	Checkout(ctx context.Context, branch string) error
+	// CheckoutTracking creates a local branch tracking the branch of the same
+	// name on remote and checks it out.
+	CheckoutTracking(ctx context.Context, remote, branch string) error
+	// CheckoutDetached checks out a tag or commit with HEAD detached at it.
+	CheckoutDetached(ctx context.Context, rev string) error
+	// CreateBranch creates a branch starting from base, or from HEAD if base
+	// is empty.
+	CreateBranch(ctx context.Context, branch, base string) error
+	// HasLocalChanges reports whether tracked files have uncommitted
+	// changes.
+	HasLocalChanges(ctx context.Context) (bool, error)
+	// GetStatus returns the status of the working tree.
+	GetStatus(ctx context.Context) (Status, error)
+	// StashPush stashes the uncommitted changes with message and returns the
+	// hash of the stash, which is empty when there was nothing to stash.
+	StashPush(ctx context.Context, message string) (string, error)
+	// StashPop applies the stash with hash and drops it.
+	StashPop(ctx context.Context, hash string) error
+	// AddWorktree creates a worktree at path with branch checked out. When
+	// remote is set, branch is created tracking the branch of the same name
+	// on remote.
+	AddWorktree(ctx context.Context, path, remote, branch string) error
+	// ListCommits returns up to limit commits reachable from ref, most
+	// recent first, described like branches named after their abbreviated
+	// hash.
+	ListCommits(ctx context.Context, ref string, limit int) ([]Branch, error)
+}
+
+// ExecBackend is a Backend that runs the git executable.
+type ExecBackend struct{}
+
+var _ Backend = ExecBackend{}
+
+func (ExecBackend) GetRepositoryPath(ctx context.Context) (string, error) {
+	return GetRepositoryPath(ctx)
+}
+
+func (ExecBackend) GetCurrentBranch(ctx context.Context) (string, error) {
+	return GetCurrentBranch(ctx)
+}
+
+func (ExecBackend) GetHead(ctx context.Context) (Head, error) {
+	return GetHead(ctx)
+}
+
+func (ExecBackend) ListBranches(ctx context.Context) ([]Branch, error) {
+	return ListBranches(ctx)
+}
+
+func (ExecBackend) ListTags(ctx context.Context) ([]Branch, error) {
+	return ListTags(ctx)
+}
+
+func (ExecBackend) ListRemotes(ctx context.Context) ([]string, error) {
+	return ListRemotes(ctx)
+}
+
+func (ExecBackend) ListCheckouts(ctx context.Context) ([]CheckoutEntry, error) {
+	return ListCheckouts(ctx)
+}
+
+func (ExecBackend) ListWorktrees(ctx context.Context) ([]Worktree, error) {
+	return ListWorktrees(ctx)
+}
+
+func (ExecBackend) Checkout(ctx context.Context, branch string) error {
+	return Checkout(ctx, branch)
+}
+
+func (ExecBackend) CheckoutTracking(ctx context.Context, remote, branch string) error {
+	return CheckoutTracking(ctx, remote, branch)
+}
+
+func (ExecBackend) CheckoutDetached(ctx context.Context, rev string) error {
+	return CheckoutDetached(ctx, rev)
+}
+
+func (ExecBackend) CreateBranch(ctx context.Context, branch, base string) error {
+	return CreateBranch(ctx, branch, base)
+}
+
+func (ExecBackend) HasLocalChanges(ctx context.Context) (bool, error) {
+	return HasLocalChanges(ctx)
+}
+
+func (ExecBackend) GetStatus(ctx context.Context) (Status, error) {
+	return GetStatus(ctx)
+}
+
+func (ExecBackend) StashPush(ctx context.Context, message string) (string, error) {
+	return StashPush(ctx, message)
+}
+
+func (ExecBackend) StashPop(ctx context.Context, hash string) error {
+	return StashPop(ctx, hash)
+}
+
+func (ExecBackend) AddWorktree(ctx context.Context, path, remote, branch string) error {
+	return AddWorktree(ctx, path, remote, branch)
+}
+
+func (ExecBackend) ListCommits(ctx context.Context, ref string, limit int) ([]Branch, error) {
+	return ListCommits(ctx, ref, limit)
+}
+
+const (
+	// BackendExec selects the ExecBackend.
+	BackendExec = "exec"
+	// BackendGoGit selects the GoGitBackend.
+	BackendGoGit = "go-git"
+)
+
+// NewBackend returns the backend called name for the repository in the
+// working directory, or ErrNotARepository when there is none.
+func NewBackend(ctx context.Context, name string) (Backend, error) {
+	switch name {
+	case BackendExec, "":
+		if ok, _ := IsGitRepository(ctx); !ok {
+			return nil, ErrNotARepository
+		}
+		return ExecBackend{}, nil
+	case BackendGoGit:
+		return OpenGoGitBackend(".")
+	default:
+		return nil, fmt.Errorf("unknown backend: %v", name)
+	}
+}
@@ -1,21 +1,66 @@
 package git
 
 import (
+	"context"
+	"strconv"
 	"strings"
-
-	"github.com/samber/lo"
+	"time"
 )
 
-func PruneRemoteBranches() error {
-	remotes, err := ListRemotes()
+// Branch describes a single branch along with information about the commit
+// at its tip. A branch may exist locally, on one or more remotes, or both.
+type Branch struct {
+	// Name is the branch name without any remote prefix.
+	Name string
+	// Ref is the full ref name, e.g. refs/heads/main. For branches that only
+	// exist on remotes this is the ref of the first remote listed in
+	// Remotes.
+	Ref string
+	// Local is true when the branch exists locally.
+	Local bool
+	// Remotes lists the remotes the branch exists on.
+	Remotes []string
+	// Upstream is the short name of the branch's upstream, if any.
+	Upstream string
+	// Hash is the full hash of the commit at the tip of the branch.
+	Hash string
+	// CommitterDate is the date the tip commit was committed.
+	CommitterDate time.Time
+	// Author is the name of the author of the tip commit.
+	Author string
+	// Subject is the subject line of the tip commit.
+	Subject string
+}
+
+// IsRemoteOnly reports whether the branch only exists on remotes.
+func (b Branch) IsRemoteOnly() bool {
+	return !b.Local && len(b.Remotes) > 0
+}
+
+// branchFormat is the for-each-ref format used by ListBranches. Fields are
+// separated by NUL bytes so that commit subjects can contain anything.
+const branchFormat = "%(refname)%00%(refname:short)%00%(symref)%00%(upstream:short)%00%(objectname)%00%(committerdate:unix)%00%(authorname)%00%(contents:subject)"
+
+// Fetch fetches the branches of every remote. With prune, remote branches
+// that no longer exist on their remote are removed too.
+func Fetch(ctx context.Context, prune bool) error {
+	args := []string{"fetch", "--all", "--quiet"}
+	if prune {
+		args = append(args, "--prune")
+	}
+
+	_, err := run(ctx, args...)
+	return err
+}
+
+func PruneRemoteBranches(ctx context.Context) error {
+	remotes, err := ListRemotes(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, remote := range remotes {
-		out, err := executeHide("remote prune %v", remote)
-		if err != nil {
-			print(out)
+		if _, err := run(ctx, "remote", "prune", remote); err != nil {
 			return err
 		}
 	}
@@ -23,43 +68,108 @@ func PruneRemoteBranches() error {
 	return nil
 }
 
-func ListBranches() ([]string, error) {
-	remotes, err := ListRemotes()
+func ListBranches(ctx context.Context) ([]Branch, error) {
+	remotes, err := ListRemotes(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := executeHide("branch -a --format=%%(refname:short)")
+	out, err := run(ctx, "for-each-ref", "--format="+branchFormat, "refs/heads", "refs/remotes")
 	if err != nil {
-		print(out)
 		return nil, err
 	}
 
-	results := strings.Split(out, "\n")
+	refs := []Branch{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 8 {
+			continue
+		}
+
+		// Skip symbolic refs such as refs/remotes/origin/HEAD.
+		if fields[2] != "" {
+			continue
+		}
+
+		branch := Branch{
+			Name:     fields[1],
+			Ref:      fields[0],
+			Local:    strings.HasPrefix(fields[0], "refs/heads/"),
+			Upstream: fields[3],
+			Hash:     fields[4],
+			Author:   fields[6],
+			Subject:  fields[7],
+		}
 
-	return lo.Uniq(lo.FilterMap(results, func(b string, _ int) (string, bool) {
-		// Don't include branches that are just the remotes themselves.
-		if lo.Contains(remotes, b) {
-			return "", false
+		if unix, err := strconv.ParseInt(fields[5], 10, 64); err == nil {
+			branch.CommitterDate = time.Unix(unix, 0)
 		}
 
-		// Remove the remote from each branch if it's prefixed with it
-		for _, remote := range remotes {
-			if strings.HasPrefix(b, remote) {
-				return strings.TrimPrefix(b, remote+"/"), true
+		refs = append(refs, branch)
+	}
+
+	return mergeBranches(refs, remotes), nil
+}
+
+// mergeBranches merges the local and remote branch refs that share a name
+// into a single Branch. refs must be named by their short ref name, e.g.
+// origin/main, and sorted with local branches first.
+func mergeBranches(refs []Branch, remotes []string) []Branch {
+	var (
+		branches = []Branch{}
+		byName   = map[string]int{}
+	)
+
+	for _, branch := range refs {
+		// Remove the remote from each remote branch, preferring the longest
+		// matching remote since remote names may themselves contain slashes.
+		if !branch.Local {
+			var remote string
+			for _, r := range remotes {
+				if strings.HasPrefix(branch.Name, r+"/") && len(r) > len(remote) {
+					remote = r
+				}
 			}
+			branch.Name = strings.TrimPrefix(branch.Name, remote+"/")
+			branch.Remotes = []string{remote}
+		}
+
+		// Local branches are listed first, so the local branch's details
+		// take precedence over those of remote branches with the same name.
+		if idx, ok := byName[branch.Name]; ok {
+			branches[idx].Remotes = append(branches[idx].Remotes, branch.Remotes...)
+			continue
 		}
 
-		return b, true
-	})), nil
+		byName[branch.Name] = len(branches)
+		branches = append(branches, branch)
+	}
+
+	return branches
 }
 
-func GetCurrentBranch() (string, error) {
-	res, err := executeHide("branch %v", "--show-current")
+func GetCurrentBranch(ctx context.Context) (string, error) {
+	res, err := run(ctx, "branch", "--show-current")
 	if err != nil {
-		print(res)
-		return res, err
+		return "", err
 	}
 
 	return strings.TrimSpace(res), nil
 }
+
+// CreateBranch creates a branch starting from base, or from HEAD if base is
+// empty. The new branch doesn't track base.
+func CreateBranch(ctx context.Context, branch, base string) error {
+	if base == "" {
+		base = "HEAD"
+	}
+
+	_, err := run(ctx, "branch", "--no-track", branch, base)
+	return err
+}
+
+// PushSetUpstream pushes branch to remote and sets it as the branch's
+// upstream.
+func PushSetUpstream(ctx context.Context, remote, branch string) error {
+	return runWithStdout(ctx, "push", "--set-upstream", remote, branch)
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+func TestMergeBranches(t *testing.T) {
+	local := func(name, subject string) Branch {
+		return Branch{Name: name, Ref: "refs/heads/" + name, Local: true, Subject: subject}
+	}
+	remote := func(name, subject string) Branch {
+		return Branch{Name: name, Ref: "refs/remotes/" + name, Subject: subject}
+	}
+
+	tests := []struct {
+		name    string
+		refs    []Branch
+		remotes []string
+		want    []Branch
+	}{
+		{
+			name: "local branches first",
+			refs: []Branch{
+				local("main", "Local"),
+				local("feature", "Feature"),
+				remote("origin/main", "Remote"),
+				remote("origin/remote-only", "Remote only"),
+				remote("upstream/main", "Upstream"),
+			},
+			remotes: []string{"origin", "upstream"},
+			want: []Branch{
+				{Name: "main", Ref: "refs/heads/main", Local: true, Subject: "Local", Remotes: []string{"origin", "upstream"}},
+				{Name: "feature", Ref: "refs/heads/feature", Local: true, Subject: "Feature"},
+				{Name: "remote-only", Ref: "refs/remotes/origin/remote-only", Subject: "Remote only", Remotes: []string{"origin"}},
+			},
+		},
+		{
+			name: "remotes with slashes",
+			refs: []Branch{
+				remote("team/fork/main", "Fork"),
+				remote("team/main", "Team"),
+				remote("team/fork/feature/login", "Login"),
+			},
+			remotes: []string{"team", "team/fork"},
+			want: []Branch{
+				{Name: "main", Ref: "refs/remotes/team/fork/main", Subject: "Fork", Remotes: []string{"team/fork", "team"}},
+				{Name: "feature/login", Ref: "refs/remotes/team/fork/feature/login", Subject: "Login", Remotes: []string{"team/fork"}},
+			},
+		},
+		{
+			name: "branch named like a remote",
+			refs: []Branch{
+				local("origin/main", "Local"),
+				remote("origin/origin/main", "Remote"),
+			},
+			remotes: []string{"origin"},
+			want: []Branch{
+				{Name: "origin/main", Ref: "refs/heads/origin/main", Local: true, Subject: "Local", Remotes: []string{"origin"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeBranches(tt.refs, tt.remotes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeBranches = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListBranchesSkipsSymbolicRefs(t *testing.T) {
+	origin := newFixture(t)
+	origin.git("init", "-b", "main")
+	origin.commit("First", "2024-01-01T10:00:00Z")
+	origin.git("branch", "feature")
+
+	// Cloning creates refs/remotes/origin/HEAD pointing at origin/main.
+	repo := &fixture{t: t, dir: t.TempDir()}
+	repo.git("clone", origin.dir, ".")
+	t.Chdir(repo.dir)
+
+	branches, err := ListBranches(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := lo.Map(branches, func(b Branch, _ int) string {
+		return b.Name
+	})
+	if want := []string{"main", "feature"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("branches = %v, want %v", names, want)
+	}
+
+	if !reflect.DeepEqual(branches[0].Remotes, []string{"origin"}) {
+		t.Errorf("main is on %v, want origin", branches[0].Remotes)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	origin := newFixture(t)
+	origin.git("init", "-b", "main")
+	origin.commit("First", "2024-01-01T10:00:00Z")
+	origin.git("branch", "old")
+
+	repo := &fixture{t: t, dir: t.TempDir()}
+	repo.git("clone", origin.dir, ".")
+	t.Chdir(repo.dir)
+
+	origin.git("branch", "new")
+	origin.git("branch", "-D", "old")
+
+	names := func() []string {
+		branches, err := ListBranches(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lo.Map(branches, func(b Branch, _ int) string {
+			return b.Name
+		})
+	}
+
+	if err := Fetch(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(), []string{"main", "new", "old"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("branches after fetching = %v, want %v", got, want)
+	}
+
+	if err := Fetch(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(), []string{"main", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("branches after fetching with prune = %v, want %v", got, want)
+	}
+}
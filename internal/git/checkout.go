@@ -1,11 +1,18 @@
 package git
 
-import "fmt"
+import "context"
 
-func Checkout(branch string) error {
-	return executeWithStdout("checkout %v", branch)
+func Checkout(ctx context.Context, branch string) error {
+	return runWithStdout(ctx, "checkout", branch)
 }
 
-func ExecuteCheckout(cmd string, args ...any) error {
-	return executeWithStdout(fmt.Sprintf("checkout %v", cmd), args...)
+// CheckoutTracking creates a local branch tracking the branch of the same name
+// on remote and checks it out.
+func CheckoutTracking(ctx context.Context, remote, branch string) error {
+	return runWithStdout(ctx, "checkout", "-b", branch, "--track", remote+"/"+branch)
+}
+
+// ExecuteCheckout runs `git checkout` with args exactly as given.
+func ExecuteCheckout(ctx context.Context, args []string) error {
+	return runWithStdout(ctx, append([]string{"checkout"}, args...)...)
 }
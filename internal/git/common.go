@@ -1,6 +1,9 @@
 package git
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -8,31 +11,101 @@ import (
 	"strings"
 )
 
-func executeHide(format string, args ...any) (string, error) {
-	slog.Debug("executing git command", slog.String("command", fmt.Sprintf(format, args...)))
+// Error is returned when a git command runs but exits unsuccessfully.
+type Error struct {
+	// Args are the arguments git was run with.
+	Args []string
+	// ExitCode is the exit code git exited with.
+	ExitCode int
+	// Stderr is everything git wrote to stderr. It is empty for commands
+	// whose output is forwarded to the terminal.
+	Stderr string
+}
+
+func (e *Error) Error() string {
+	stderr := strings.TrimSpace(e.Stderr)
+	if stderr == "" {
+		return fmt.Sprintf("git %v exited with code %v", strings.Join(e.Args, " "), e.ExitCode)
+	}
+
+	// git prefixes most messages with "fatal: " or "error: ", which would be
+	// redundant once the error is wrapped or printed.
+	for _, prefix := range []string{"fatal: ", "error: "} {
+		stderr = strings.TrimPrefix(stderr, prefix)
+	}
+
+	return stderr
+}
 
-	cmdFormatted := fmt.Sprintf(format, args...)
-	cmd := exec.Command("git", strings.Split(cmdFormatted, " ")...)
-	output, err := cmd.CombinedOutput()
+// newError converts the error returned by running cmd into an *Error when git
+// ran but failed, keeping any other error (such as git not being found)
+// as is.
+func newError(args []string, stderr string, err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
 
-	slog.Debug("git command output", slog.String("command", cmdFormatted), slog.String("output", string(output)))
+	return &Error{
+		Args:     args,
+		ExitCode: exitErr.ExitCode(),
+		Stderr:   stderr,
+	}
+}
 
-	return string(output), err
+// run runs git with args and returns what it wrote to stdout. If git fails,
+// the returned error is an *Error holding what it wrote to stderr.
+func run(ctx context.Context, args ...string) (string, error) {
+	return runWithEnv(ctx, nil, args...)
 }
 
-func executeWithStdout(format string, args ...any) error {
-	slog.Debug("executing git command", slog.String("command", fmt.Sprintf(format, args...)))
+// runWithEnv runs git like run does, with env added to its environment.
+func runWithEnv(ctx context.Context, env []string, args ...string) (string, error) {
+	slog.Debug("executing git command", slog.Any("args", args))
 
-	cmdFormatted := fmt.Sprintf(format, args...)
-	cmd := exec.Command("git", strings.Split(cmdFormatted, " ")...)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	slog.Debug("git command output",
+		slog.Any("args", args),
+		slog.String("stdout", stdout.String()),
+		slog.String("stderr", stderr.String()),
+		slog.Any("error", err),
+	)
+
+	if err != nil {
+		return stdout.String(), newError(args, stderr.String(), err)
+	}
+
+	return stdout.String(), nil
+}
+
+// runWithStdout runs git with args, forwarding its output to stdout and
+// stderr so the user sees it as if they had run git themselves.
+func runWithStdout(ctx context.Context, args ...string) error {
+	slog.Debug("executing git command", slog.Any("args", args))
+
+	cmd := exec.CommandContext(ctx, "git", args...)
 
 	// Forward output to stdout and stderr
+	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	err := cmd.Run()
 
-	slog.Debug("git command finished", slog.String("command", cmdFormatted), slog.Any("error", err))
+	slog.Debug("git command finished", slog.Any("args", args), slog.Any("error", err))
+
+	if err != nil {
+		return newError(args, "", err)
+	}
 
-	return err
+	return nil
 }
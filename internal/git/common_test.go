@@ -0,0 +1,86 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   string
+	}{
+		{"", "git branch -d main exited with code 1"},
+		{"\n", "git branch -d main exited with code 1"},
+		{"fatal: not a git repository\n", "not a git repository"},
+		{"error: branch 'main' not found.\n", "branch 'main' not found."},
+		{"warning: something odd\n", "warning: something odd"},
+		{"error: first line\nhint: second line\n", "first line\nhint: second line"},
+	}
+
+	for _, tt := range tests {
+		err := &Error{Args: []string{"branch", "-d", "main"}, ExitCode: 1, Stderr: tt.stderr}
+		if got := err.Error(); got != tt.want {
+			t.Errorf("Error() with stderr %q = %q, want %q", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestNewError(t *testing.T) {
+	// Errors other than git exiting unsuccessfully are kept as is.
+	notFound := &exec.Error{Name: "git", Err: exec.ErrNotFound}
+	if err := newError([]string{"status"}, "", notFound); err != notFound {
+		t.Errorf("newError when git isn't found = %v, want %v", err, notFound)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	exitErr := exec.Command("git", "--no-such-option").Run()
+
+	var gitErr *Error
+	err := newError([]string{"--no-such-option"}, "unknown option\n", exitErr)
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("newError = %#v, want an *Error", err)
+	}
+
+	want := &Error{Args: []string{"--no-such-option"}, ExitCode: 129, Stderr: "unknown option\n"}
+	if !reflect.DeepEqual(gitErr, want) {
+		t.Errorf("newError = %+v, want %+v", gitErr, want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	var gitErr *Error
+	_, err := run(ctx, "rev-parse", "--verify", "no-such-branch")
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("run = %#v, want an *Error", err)
+	}
+
+	if gitErr.ExitCode != 128 || !strings.HasPrefix(gitErr.Stderr, "fatal: ") {
+		t.Errorf("run = %+v, want exit code 128 and git's message", gitErr)
+	}
+	if strings.HasPrefix(gitErr.Error(), "fatal: ") {
+		t.Errorf("Error() = %q, want it without the fatal prefix", gitErr.Error())
+	}
+
+	// Nothing is captured from commands whose output is forwarded.
+	err = runWithStdout(ctx, "rev-parse", "--verify", "--quiet", "no-such-branch")
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("runWithStdout = %#v, want an *Error", err)
+	}
+
+	want := &Error{Args: []string{"rev-parse", "--verify", "--quiet", "no-such-branch"}, ExitCode: 1}
+	if !reflect.DeepEqual(gitErr, want) {
+		t.Errorf("runWithStdout = %+v, want %+v", gitErr, want)
+	}
+}
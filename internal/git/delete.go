@@ -0,0 +1,38 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrBranchNotMerged = errors.New("branch is not fully merged")
+)
+
+// DeleteBranch deletes a local branch. Unless force is set, git refuses to
+// delete a branch that isn't fully merged, in which case ErrBranchNotMerged is
+// returned.
+func DeleteBranch(ctx context.Context, branch string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+
+	// git's messages are translated, so it's run in the C locale for the one
+	// saying the branch isn't fully merged to be recognized.
+	_, err := runWithEnv(ctx, []string{"LC_ALL=C"}, "branch", flag, branch)
+
+	var gitErr *Error
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.Stderr, "not fully merged") {
+		return ErrBranchNotMerged
+	}
+
+	return err
+}
+
+// DeleteRemoteBranch deletes a branch from a remote.
+func DeleteRemoteBranch(ctx context.Context, remote, branch string) error {
+	_, err := run(ctx, "push", remote, "--delete", branch)
+	return err
+}
@@ -0,0 +1,41 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDeleteBranch(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.git("branch", "merged")
+	repo.git("checkout", "-b", "unmerged")
+	repo.commit("Second", "2024-01-02T10:00:00Z")
+	repo.git("checkout", "main")
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	// git says the branch isn't fully merged in German with these, when its
+	// translations are installed.
+	t.Setenv("LANGUAGE", "de")
+	t.Setenv("LC_ALL", "C.UTF-8")
+
+	if err := DeleteBranch(ctx, "merged", false); err != nil {
+		t.Errorf("DeleteBranch(merged) = %v", err)
+	}
+
+	if err := DeleteBranch(ctx, "unmerged", false); !errors.Is(err, ErrBranchNotMerged) {
+		t.Errorf("DeleteBranch(unmerged) = %v, want %v", err, ErrBranchNotMerged)
+	}
+
+	if err := DeleteBranch(ctx, "unmerged", true); err != nil {
+		t.Errorf("DeleteBranch(unmerged) with force = %v", err)
+	}
+
+	var gitErr *Error
+	if err := DeleteBranch(ctx, "unmerged", false); !errors.As(err, &gitErr) || errors.Is(err, ErrBranchNotMerged) {
+		t.Errorf("DeleteBranch of a missing branch = %v, want a git error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -16,20 +17,18 @@ func ValidateGitInstallation() error {
 	return err
 }
 
-func IsGitRepository() (bool, error) {
-	res, err := executeHide("rev-parse --is-inside-work-tree")
+func IsGitRepository(ctx context.Context) (bool, error) {
+	res, err := run(ctx, "rev-parse", "--is-inside-work-tree")
 	if err != nil {
-		print(res)
 		return false, err
 	}
 
 	return strings.TrimSpace(res) == "true", nil
 }
 
-func GetRepositoryPath() (string, error) {
-	res, err := executeHide("rev-parse --show-toplevel")
+func GetRepositoryPath(ctx context.Context) (string, error) {
+	res, err := run(ctx, "rev-parse", "--show-toplevel")
 	if err != nil {
-		print(res)
 		return "", err
 	}
 
@@ -0,0 +1,291 @@
+// Package gittest provides an in-memory git.Backend for tests.
+package gittest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// Backend is an in-memory git.Backend. Its fields can be set up directly and
+// are updated as branches are checked out and created, so that tests can
+// inspect them afterwards.
+type Backend struct {
+	// RepositoryPath is the path returned by GetRepositoryPath.
+	RepositoryPath string
+	// CurrentBranch is the branch that is checked out.
+	CurrentBranch string
+	// Detached is the hash of the commit HEAD is detached at when
+	// CurrentBranch is empty.
+	Detached string
+	// Branches are the branches of the repository.
+	Branches []git.Branch
+	// Tags are the tags of the repository, which can be checked out
+	// detached.
+	Tags []git.Branch
+	// Remotes are the remotes of the repository.
+	Remotes []string
+	// Checkouts are the checkouts recorded in the reflog, most recent first.
+	// Every checkout made through the backend is added to the front.
+	Checkouts []git.CheckoutEntry
+	// Worktrees are the worktrees of the repository, the main worktree
+	// first.
+	Worktrees []git.Worktree
+	// LocalChanges is the uncommitted change in the working tree, empty when
+	// there is none. It is carried along when checking out a branch, and
+	// moved in and out of Stashes when stashing.
+	LocalChanges string
+	// Stashes are the stashes, most recent first.
+	Stashes []Stash
+	// Commits maps refs to the commits reachable from them, most recent
+	// first.
+	Commits map[string][]git.Branch
+
+	// pushed counts the stashes pushed, to give each a unique hash.
+	pushed int
+}
+
+// Stash is a stash held by a Backend.
+type Stash struct {
+	// Hash identifies the stash.
+	Hash string
+	// Message is the message the stash was pushed with.
+	Message string
+	// Changes are the changes that were stashed.
+	Changes string
+}
+
+var _ git.Backend = (*Backend)(nil)
+
+// New returns a Backend for a repository at path with the given local
+// branches, the first of which is checked out.
+func New(path string, branches ...string) *Backend {
+	b := &Backend{RepositoryPath: path}
+	for _, branch := range branches {
+		b.Branches = append(b.Branches, git.Branch{Name: branch, Ref: "refs/heads/" + branch, Local: true})
+	}
+
+	if len(branches) > 0 {
+		b.CurrentBranch = branches[0]
+	}
+
+	return b
+}
+
+// AddRemoteBranch adds branch to remote, creating the remote if needed.
+func (b *Backend) AddRemoteBranch(remote, branch string) {
+	if !lo.Contains(b.Remotes, remote) {
+		b.Remotes = append(b.Remotes, remote)
+	}
+
+	if idx := b.index(branch); idx >= 0 {
+		b.Branches[idx].Remotes = append(b.Branches[idx].Remotes, remote)
+		return
+	}
+
+	b.Branches = append(b.Branches, git.Branch{
+		Name:    branch,
+		Ref:     "refs/remotes/" + remote + "/" + branch,
+		Remotes: []string{remote},
+	})
+}
+
+func (b *Backend) GetRepositoryPath(context.Context) (string, error) {
+	return b.RepositoryPath, nil
+}
+
+func (b *Backend) GetCurrentBranch(context.Context) (string, error) {
+	return b.CurrentBranch, nil
+}
+
+func (b *Backend) GetHead(context.Context) (git.Head, error) {
+	if b.CurrentBranch != "" || b.Detached == "" {
+		return git.Head{Branch: b.CurrentBranch}, nil
+	}
+
+	return git.Head{Hash: b.Detached, ShortHash: b.Detached[:min(len(b.Detached), 7)]}, nil
+}
+
+func (b *Backend) ListBranches(context.Context) ([]git.Branch, error) {
+	return append([]git.Branch{}, b.Branches...), nil
+}
+
+func (b *Backend) ListTags(context.Context) ([]git.Branch, error) {
+	return b.Tags, nil
+}
+
+func (b *Backend) ListRemotes(context.Context) ([]string, error) {
+	return append([]string{}, b.Remotes...), nil
+}
+
+func (b *Backend) ListCheckouts(context.Context) ([]git.CheckoutEntry, error) {
+	return append([]git.CheckoutEntry{}, b.Checkouts...), nil
+}
+
+func (b *Backend) ListWorktrees(context.Context) ([]git.Worktree, error) {
+	return b.Worktrees, nil
+}
+
+func (b *Backend) Checkout(_ context.Context, branch string) error {
+	idx := b.index(branch)
+	if idx < 0 || !b.Branches[idx].Local {
+		return failure(1, "error: pathspec '%v' did not match any file(s) known to git", branch)
+	}
+
+	b.Checkouts = append([]git.CheckoutEntry{{From: b.head(), To: branch}}, b.Checkouts...)
+	b.CurrentBranch = branch
+	b.Detached = ""
+	return nil
+}
+
+func (b *Backend) CheckoutTracking(ctx context.Context, remote, branch string) error {
+	idx := b.index(branch)
+	if idx < 0 || !lo.Contains(b.Branches[idx].Remotes, remote) {
+		return failure(128, "fatal: '%v/%v' is not a commit and a branch '%v' cannot be created from it", remote, branch, branch)
+	}
+	if b.Branches[idx].Local {
+		return failure(128, "fatal: a branch named '%v' already exists", branch)
+	}
+
+	b.Branches[idx].Local = true
+	b.Branches[idx].Ref = "refs/heads/" + branch
+	b.Branches[idx].Upstream = remote + "/" + branch
+	return b.Checkout(ctx, branch)
+}
+
+func (b *Backend) CheckoutDetached(_ context.Context, rev string) error {
+	hash := rev
+	if tag, ok := lo.Find(b.Tags, func(tag git.Branch) bool { return tag.Ref == rev }); ok {
+		hash = tag.Hash
+	} else if !git.IsFullHash(rev) {
+		return failure(128, "fatal: invalid reference: %v", rev)
+	}
+
+	b.Checkouts = append([]git.CheckoutEntry{{From: b.head(), To: hash}}, b.Checkouts...)
+	b.CurrentBranch = ""
+	b.Detached = hash
+	return nil
+}
+
+func (b *Backend) CreateBranch(_ context.Context, branch, base string) error {
+	if b.index(branch) >= 0 {
+		return failure(128, "fatal: a branch named '%v' already exists", branch)
+	}
+	if base != "" && !lo.ContainsBy(b.Branches, func(other git.Branch) bool {
+		return other.Ref == base
+	}) {
+		return failure(128, "fatal: not a valid object name: '%v'", base)
+	}
+
+	b.Branches = append(b.Branches, git.Branch{Name: branch, Ref: "refs/heads/" + branch, Local: true})
+	return nil
+}
+
+func (b *Backend) HasLocalChanges(context.Context) (bool, error) {
+	return b.LocalChanges != "", nil
+}
+
+// GetStatus reports LocalChanges as a single file with unstaged changes.
+func (b *Backend) GetStatus(context.Context) (git.Status, error) {
+	if b.LocalChanges == "" {
+		return git.Status{}, nil
+	}
+
+	return git.Status{Unstaged: 1}, nil
+}
+
+func (b *Backend) StashPush(_ context.Context, message string) (string, error) {
+	if b.LocalChanges == "" {
+		return "", nil
+	}
+
+	b.pushed++
+	stash := Stash{
+		Hash:    fmt.Sprintf("stash-%v", b.pushed),
+		Message: message,
+		Changes: b.LocalChanges,
+	}
+	b.Stashes = append([]Stash{stash}, b.Stashes...)
+	b.LocalChanges = ""
+	return stash.Hash, nil
+}
+
+func (b *Backend) StashPop(_ context.Context, hash string) error {
+	_, idx, found := lo.FindIndexOf(b.Stashes, func(stash Stash) bool {
+		return stash.Hash == hash
+	})
+	if !found {
+		return git.ErrStashNotFound
+	}
+	if b.LocalChanges != "" {
+		return failure(1, "error: Your local changes would be overwritten by merge.")
+	}
+
+	b.LocalChanges = b.Stashes[idx].Changes
+	b.Stashes = append(b.Stashes[:idx], b.Stashes[idx+1:]...)
+	return nil
+}
+
+// AddWorktree adds a worktree at path to Worktrees, with branch checked out.
+func (b *Backend) AddWorktree(_ context.Context, path, remote, branch string) error {
+	if lo.ContainsBy(b.Worktrees, func(w git.Worktree) bool { return w.Path == path }) {
+		return failure(128, "fatal: '%v' already exists", path)
+	}
+
+	idx := b.index(branch)
+	if remote != "" {
+		if idx < 0 || !lo.Contains(b.Branches[idx].Remotes, remote) {
+			return failure(128, "fatal: invalid reference: %v/%v", remote, branch)
+		}
+		if b.Branches[idx].Local {
+			return failure(128, "fatal: a branch named '%v' already exists", branch)
+		}
+
+		b.Branches[idx].Local = true
+		b.Branches[idx].Ref = "refs/heads/" + branch
+		b.Branches[idx].Upstream = remote + "/" + branch
+	} else if idx < 0 || !b.Branches[idx].Local {
+		return failure(128, "fatal: invalid reference: %v", branch)
+	} else if branch == b.CurrentBranch || lo.ContainsBy(b.Worktrees, func(w git.Worktree) bool { return w.Branch == branch }) {
+		return failure(128, "fatal: '%v' is already checked out", branch)
+	}
+
+	b.Worktrees = append(b.Worktrees, git.Worktree{Path: path, Hash: b.Branches[idx].Hash, Branch: branch})
+	return nil
+}
+
+func (b *Backend) ListCommits(_ context.Context, ref string, limit int) ([]git.Branch, error) {
+	commits, ok := b.Commits[ref]
+	if !ok {
+		return nil, failure(128, "fatal: bad revision '%v'", ref)
+	}
+
+	return append([]git.Branch{}, commits[:min(len(commits), limit)]...), nil
+}
+
+// head returns what is checked out as the reflog records it when leaving
+// it: the branch, or the full hash of the commit HEAD is detached at.
+func (b *Backend) head() string {
+	if b.CurrentBranch == "" && b.Detached != "" {
+		return b.Detached
+	}
+
+	return b.CurrentBranch
+}
+
+// index returns the index of the branch called name, or -1.
+func (b *Backend) index(name string) int {
+	_, idx, _ := lo.FindIndexOf(b.Branches, func(branch git.Branch) bool {
+		return branch.Name == name
+	})
+
+	return idx
+}
+
+// failure returns the error git fails with when it exits with exitCode after
+// printing the message.
+func failure(exitCode int, format string, args ...any) error {
+	return &git.Error{ExitCode: exitCode, Stderr: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,376 @@
+package git
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/samber/lo"
+)
+
+// GoGitBackend is a Backend that reads the repository directly instead of
+// running git, which saves starting a process for every call on large
+// repositories and slow file systems. Branches are still checked out and
+// created by running git so that hooks run and the work tree is updated
+// exactly as git itself would.
+//
+// Only reading refs, HEAD, the reflog, the config and the worktrees is done
+// directly. git is still run to:
+//   - describe the commit HEAD is detached at, in GetHead;
+//   - compare the work tree with the index, in GetStatus and
+//     HasLocalChanges, since git keeps a stat cache go-git doesn't use;
+//   - check out, create branches and stash, which change the repository.
+type GoGitBackend struct {
+	ExecBackend
+
+	repo    *gogit.Repository
+	storage *filesystem.Storage
+}
+
+var _ Backend = (*GoGitBackend)(nil)
+
+// OpenGoGitBackend opens the repository containing path.
+func OpenGoGitBackend(path string) (*GoGitBackend, error) {
+	repo, err := gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if errors.Is(err, gogit.ErrRepositoryNotExists) {
+		return nil, ErrNotARepository
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, errors.New("repository is not stored on the file system")
+	}
+
+	return &GoGitBackend{repo: repo, storage: storage}, nil
+}
+
+func (b *GoGitBackend) GetRepositoryPath(context.Context) (string, error) {
+	worktree, err := b.repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	// git resolves symbolic links in the path to the work tree.
+	return filepath.EvalSymlinks(worktree.Filesystem.Root())
+}
+
+func (b *GoGitBackend) GetCurrentBranch(context.Context) (string, error) {
+	head, err := b.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+
+	// HEAD points at a commit rather than a branch when it's detached.
+	if head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return "", nil
+	}
+
+	return head.Target().Short(), nil
+}
+
+// GetHead reads HEAD directly when a branch is checked out, and runs git to
+// describe the commit HEAD is detached at.
+func (b *GoGitBackend) GetHead(ctx context.Context) (Head, error) {
+	branch, err := b.GetCurrentBranch(ctx)
+	if err != nil || branch != "" {
+		return Head{Branch: branch}, err
+	}
+
+	return b.ExecBackend.GetHead(ctx)
+}
+
+func (b *GoGitBackend) ListBranches(ctx context.Context) ([]Branch, error) {
+	remotes, err := b.ListRemotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := b.repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := b.repo.References()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	// Symbolic refs such as refs/remotes/origin/HEAD are skipped, like they
+	// are by the exec backend.
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && (ref.Name().IsBranch() || ref.Name().IsRemote()) {
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort like for-each-ref does, which puts local branches first.
+	slices.SortFunc(refs, func(a, b *plumbing.Reference) int {
+		return strings.Compare(a.Name().String(), b.Name().String())
+	})
+
+	branches := lo.Map(refs, func(ref *plumbing.Reference, _ int) Branch {
+		branch := Branch{
+			Name:  ref.Name().Short(),
+			Ref:   ref.Name().String(),
+			Local: ref.Name().IsBranch(),
+			Hash:  ref.Hash().String(),
+		}
+
+		if branch.Local {
+			branch.Upstream = upstream(cfg, branch.Name)
+		}
+
+		if commit, err := b.repo.CommitObject(ref.Hash()); err == nil {
+			branch.CommitterDate = time.Unix(commit.Committer.When.Unix(), 0)
+			branch.Author = commit.Author.Name
+			branch.Subject = subject(commit.Message)
+		}
+
+		return branch
+	})
+
+	return mergeBranches(branches, remotes), nil
+}
+
+func (b *GoGitBackend) ListTags(context.Context) ([]Branch, error) {
+	iter, err := b.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(refs, func(a, b *plumbing.Reference) int {
+		return strings.Compare(a.Name().String(), b.Name().String())
+	})
+
+	tags := []Branch{}
+	for _, ref := range refs {
+		// Annotated tags are described by the object they point at, which
+		// has to be a commit like for the exec backend.
+		hash := ref.Hash()
+		if tag, err := b.repo.TagObject(hash); err == nil {
+			hash = tag.Target
+		}
+
+		commit, err := b.repo.CommitObject(hash)
+		if err != nil {
+			continue
+		}
+
+		tags = append(tags, Branch{
+			Name:          ref.Name().Short(),
+			Ref:           ref.Name().String(),
+			Hash:          hash.String(),
+			CommitterDate: time.Unix(commit.Committer.When.Unix(), 0),
+			Author:        commit.Author.Name,
+			Subject:       subject(commit.Message),
+		})
+	}
+
+	return tags, nil
+}
+
+func (b *GoGitBackend) ListRemotes(context.Context) ([]string, error) {
+	cfg, err := b.repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := lo.Keys(cfg.Remotes)
+	slices.Sort(remotes)
+
+	return remotes, nil
+}
+
+func (b *GoGitBackend) ListCheckouts(context.Context) ([]CheckoutEntry, error) {
+	f, err := b.storage.Filesystem().Open(filepath.Join("logs", "HEAD"))
+	if err != nil {
+		// A repository without any commits has no reflog yet.
+		return []CheckoutEntry{}, nil
+	}
+	defer f.Close()
+
+	// Each line holds the old and new commit, who made the change and when,
+	// followed by a tab and the message. Entries are oldest first.
+	entries := []CheckoutEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		_, message, _ := strings.Cut(scanner.Text(), "\t")
+		if entry, ok := parseCheckout(message); ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+func (b *GoGitBackend) ListWorktrees(context.Context) ([]Worktree, error) {
+	cfg, err := b.repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	// A linked worktree has its own git directory, with a commondir file
+	// pointing at the git directory of the main worktree.
+	gitDir := b.storage.Filesystem().Root()
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolvePath(gitDir, strings.TrimSpace(string(data)))
+	}
+
+	main := Worktree{Path: commonDir, Bare: true}
+	if !cfg.Core.IsBare {
+		path, err := filepath.EvalSymlinks(commonDir)
+		if err != nil {
+			return nil, err
+		}
+		main, err = b.worktree(strings.TrimSuffix(path, string(filepath.Separator)+".git"), commonDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Each linked worktree has a directory in worktrees, with a gitdir file
+	// pointing at the .git file in the worktree.
+	dir := filepath.Join(commonDir, "worktrees")
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var linked []Worktree
+	for _, entry := range entries {
+		gitDir := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(gitDir, "gitdir"))
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Dir(resolvePath(gitDir, strings.TrimSpace(string(data))))
+		worktree, err := b.worktree(path, gitDir)
+		if err != nil {
+			return nil, err
+		}
+		linked = append(linked, worktree)
+	}
+
+	// git lists the main worktree first, and the others by path.
+	slices.SortFunc(linked, func(a, b Worktree) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return append([]Worktree{main}, linked...), nil
+}
+
+// worktree describes the worktree at path according to the HEAD in its git
+// directory gitDir.
+func (b *GoGitBackend) worktree(path, gitDir string) (Worktree, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	worktree := Worktree{Path: path}
+	head := strings.TrimSpace(string(data))
+	target, ok := strings.CutPrefix(head, "ref: ")
+	if !ok {
+		worktree.Hash, worktree.Detached = head, true
+		return worktree, nil
+	}
+
+	// git lists a branch without commits with the zero hash.
+	worktree.Branch = strings.TrimPrefix(target, "refs/heads/")
+	worktree.Hash = plumbing.ZeroHash.String()
+	if ref, err := storer.ResolveReference(b.repo.Storer, plumbing.ReferenceName(target)); err == nil {
+		worktree.Hash = ref.Hash().String()
+	}
+
+	return worktree, nil
+}
+
+func (b *GoGitBackend) GetStatus(ctx context.Context) (Status, error) {
+	return getStatus(ctx, b.storage.Filesystem().Root())
+}
+
+// resolvePath returns path, resolved relative to dir when it isn't absolute.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// upstream returns the short name of the upstream configured for branch, the
+// same way %(upstream:short) does for for-each-ref.
+func upstream(cfg *config.Config, branch string) string {
+	bc, ok := cfg.Branches[branch]
+	if !ok || bc.Remote == "" || bc.Merge == "" {
+		return ""
+	}
+
+	// The upstream of a branch can be another local branch.
+	if bc.Remote == "." {
+		return bc.Merge.Short()
+	}
+
+	remote, ok := cfg.Remotes[bc.Remote]
+	if !ok {
+		return ""
+	}
+
+	for _, spec := range remote.Fetch {
+		if spec.Match(bc.Merge) {
+			return spec.Dst(bc.Merge).Short()
+		}
+	}
+
+	return ""
+}
+
+// subject returns the subject of a commit message the same way
+// %(contents:subject) does for for-each-ref: its first paragraph joined into
+// a single line.
+func subject(message string) string {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	message = strings.TrimLeft(message, "\n")
+	paragraph, _, _ := strings.Cut(message, "\n\n")
+
+	return strings.ReplaceAll(strings.TrimRight(paragraph, "\n"), "\n", " ")
+}
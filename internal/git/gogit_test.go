@@ -0,0 +1,238 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixture builds a repository for a test by running git in dir.
+type fixture struct {
+	t   *testing.T
+	dir string
+}
+
+// newFixture returns a fixture for an empty directory. git is run with a
+// fixed identity and without the user's or system's config so that the
+// repositories are the same everywhere.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Ada Lovelace")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Charles Babbage")
+	t.Setenv("GIT_COMMITTER_EMAIL", "charles@example.com")
+
+	return &fixture{t: t, dir: t.TempDir()}
+}
+
+// git runs git with args in the fixture's directory.
+func (f *fixture) git(args ...string) {
+	f.t.Helper()
+
+	if out, err := f.command(args...).CombinedOutput(); err != nil {
+		f.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// command returns a command running git with args in the fixture's
+// directory, for when git is expected to fail.
+func (f *fixture) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = f.dir
+	return cmd
+}
+
+// commit makes an empty commit with message, committed at date.
+func (f *fixture) commit(message, date string) {
+	f.t.Helper()
+	f.t.Setenv("GIT_COMMITTER_DATE", date)
+	f.git("commit", "--allow-empty", "-m", message)
+}
+
+// path returns the path to name inside the fixture's directory.
+func (f *fixture) path(name string) string {
+	return filepath.Join(f.dir, name)
+}
+
+// compareBackends checks that the GoGitBackend returns exactly what the
+// ExecBackend does for the repository containing dir.
+func compareBackends(t *testing.T, dir string) {
+	t.Helper()
+	t.Chdir(dir)
+	ctx := context.Background()
+
+	goGit, err := OpenGoGitBackend(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func(Backend) (any, error)
+	}{
+		{"GetRepositoryPath", func(b Backend) (any, error) { return b.GetRepositoryPath(ctx) }},
+		{"GetCurrentBranch", func(b Backend) (any, error) { return b.GetCurrentBranch(ctx) }},
+		{"GetHead", func(b Backend) (any, error) { return b.GetHead(ctx) }},
+		{"ListBranches", func(b Backend) (any, error) { return b.ListBranches(ctx) }},
+		{"ListRemotes", func(b Backend) (any, error) { return b.ListRemotes(ctx) }},
+		{"ListCheckouts", func(b Backend) (any, error) { return b.ListCheckouts(ctx) }},
+		{"ListTags", func(b Backend) (any, error) { return b.ListTags(ctx) }},
+		{"ListWorktrees", func(b Backend) (any, error) { return b.ListWorktrees(ctx) }},
+		{"GetStatus", func(b Backend) (any, error) { return b.GetStatus(ctx) }},
+		{"HasLocalChanges", func(b Backend) (any, error) { return b.HasLocalChanges(ctx) }},
+	}
+
+	for _, tt := range tests {
+		want, err := tt.call(ExecBackend{})
+		if err != nil {
+			t.Fatalf("%v with the exec backend: %v", tt.name, err)
+		}
+
+		got, err := tt.call(goGit)
+		if err != nil {
+			t.Fatalf("%v with the go-git backend: %v", tt.name, err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v with the go-git backend = %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestGoGitBackendBranches(t *testing.T) {
+	origin := newFixture(t)
+	origin.git("init", "-b", "main")
+	origin.commit("Initial commit", "2024-01-01T10:00:00Z")
+	origin.git("branch", "feature")
+	origin.git("checkout", "-b", "remote-only")
+	origin.commit("Only on the remote\n\nWith a body.", "2024-01-02T10:00:00Z")
+	origin.git("checkout", "main")
+
+	repo := &fixture{t: t, dir: t.TempDir()}
+	repo.git("clone", origin.dir, ".")
+	repo.git("checkout", "feature")
+	repo.git("remote", "add", "team/fork", origin.dir)
+	repo.git("fetch", "team/fork")
+	repo.commit("A subject\nthat wraps\n\nAnd a body.", "2024-01-03T10:00:00Z")
+	repo.git("branch", "--track", "follows-main", "main")
+	repo.git("branch", "gone")
+	repo.git("config", "branch.gone.remote", "origin")
+	repo.git("config", "branch.gone.merge", "refs/heads/deleted")
+
+	// Pack the refs so that some are only in packed-refs, then update one so
+	// that it's both packed and loose.
+	repo.git("pack-refs", "--all")
+	repo.git("checkout", "main")
+	repo.commit("Loose commit", "2024-01-04T10:00:00Z")
+	repo.git("checkout", "--detach", "HEAD~1")
+	repo.git("checkout", "feature")
+
+	compareBackends(t, repo.dir)
+}
+
+func TestGoGitBackendDetachedHead(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.commit("Second", "2024-01-02T10:00:00Z")
+	repo.git("checkout", "--detach", "HEAD~1")
+
+	compareBackends(t, repo.dir)
+}
+
+func TestGoGitBackendEmptyRepository(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "trunk")
+
+	compareBackends(t, repo.dir)
+}
+
+func TestGoGitBackendTags(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.git("tag", "v1.0")
+	repo.commit("Second\n\nWith a body.", "2024-01-02T10:00:00Z")
+	repo.git("tag", "-a", "v2.0", "-m", "Release 2.0")
+	repo.git("tag", "-a", "v2.0-signed", "-m", "Tag of a tag", "v2.0")
+	repo.git("tag", "tree", "HEAD^{tree}")
+	repo.git("pack-refs", "--all")
+	repo.git("tag", "-a", "loose", "-m", "Loose")
+
+	compareBackends(t, repo.dir)
+}
+
+func TestGoGitBackendWorktree(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.git("worktree", "add", "-b", "other", repo.path("worktree"))
+	repo.git("worktree", "add", "--detach", repo.path("detached"))
+
+	// The status is read from the worktree's own git directory.
+	if err := os.WriteFile(repo.path("worktree/untracked"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	worktree := &fixture{t: t, dir: repo.path("worktree")}
+	worktree.git("bisect", "start")
+
+	compareBackends(t, repo.path("worktree"))
+	compareBackends(t, repo.dir)
+}
+
+func TestGoGitBackendBare(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+
+	bare := &fixture{t: t, dir: t.TempDir()}
+	bare.git("clone", "--bare", repo.dir, ".")
+	bare.git("worktree", "add", bare.path("worktree"))
+
+	compareBackends(t, bare.path("worktree"))
+}
+
+func TestGoGitBackendSubdirectory(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+
+	if err := os.Mkdir(repo.path("sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	compareBackends(t, repo.path("sub"))
+}
+
+func TestOpenGoGitBackendOutsideRepository(t *testing.T) {
+	if _, err := OpenGoGitBackend(t.TempDir()); err != ErrNotARepository {
+		t.Errorf("OpenGoGitBackend outside a repository = %v, want %v", err, ErrNotARepository)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	tests := map[string]string{
+		"Subject":                         "Subject",
+		"Subject\n":                       "Subject",
+		"Subject\n\nBody":                 "Subject",
+		"Wrapped\nsubject\n\nBody":        "Wrapped subject",
+		"\n\nLeading blank lines\n":       "Leading blank lines",
+		"Windows\r\nline endings\r\n\r\n": "Windows line endings",
+	}
+
+	for message, want := range tests {
+		if got := subject(message); got != want {
+			t.Errorf("subject(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Head describes what is checked out. HEAD is either on a branch, or
+// detached at a commit, such as after checking out a tag.
+type Head struct {
+	// Branch is the branch that is checked out, empty when HEAD is
+	// detached.
+	Branch string
+	// Hash is the full hash of the commit HEAD is detached at. It is only
+	// set when HEAD is detached.
+	Hash string
+	// ShortHash is the abbreviated Hash.
+	ShortHash string
+	// Description describes the detached commit relative to the most recent
+	// tag reachable from it, e.g. v1.2-3-gabc1234, or v1.2 when it is
+	// tagged. It is empty when no tag is reachable.
+	Description string
+}
+
+// Detached reports whether HEAD is detached at a commit.
+func (h Head) Detached() bool {
+	return h.Branch == "" && h.Hash != ""
+}
+
+// String returns the branch that is checked out, or the abbreviated hash and
+// description of the commit HEAD is detached at. It is empty in a repository
+// without commits.
+func (h Head) String() string {
+	switch {
+	case !h.Detached():
+		return h.Branch
+	case h.Description != "":
+		return fmt.Sprintf("%v (%v)", h.ShortHash, h.Description)
+	}
+
+	return h.ShortHash
+}
+
+// GetHead returns what is checked out.
+func GetHead(ctx context.Context) (Head, error) {
+	branch, err := GetCurrentBranch(ctx)
+	if err != nil || branch != "" {
+		return Head{Branch: branch}, err
+	}
+
+	// There is no commit to be detached at in a repository without commits.
+	out, err := run(ctx, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return Head{}, nil
+	}
+
+	head := Head{Hash: strings.TrimSpace(out)}
+
+	out, err = run(ctx, "rev-parse", "--short", head.Hash)
+	if err != nil {
+		return Head{}, err
+	}
+	head.ShortHash = strings.TrimSpace(out)
+
+	// describe fails when no tag is reachable, which leaves the commit
+	// without a description.
+	if out, err := run(ctx, "describe", "--tags", head.Hash); err == nil {
+		head.Description = strings.TrimSpace(out)
+	}
+
+	return head, nil
+}
+
+// CheckoutDetached checks out rev, which can be a tag or a commit, with HEAD
+// detached at it.
+func CheckoutDetached(ctx context.Context, rev string) error {
+	return runWithStdout(ctx, "checkout", "--detach", rev)
+}
+
+// IsFullHash reports whether s is a full commit hash, which is how detached
+// commits are recorded in the branch history. Unlike abbreviated hashes, full
+// hashes can't be mistaken for branch names in practice.
+func IsFullHash(s string) bool {
+	return (len(s) == 40 || len(s) == 64) && isCommitHash(s)
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetHead(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	head, err := GetHead(ctx)
+	if err != nil || head != (Head{Branch: "main"}) {
+		t.Errorf("GetHead in an empty repository = %+v, %v, want main", head, err)
+	}
+
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.git("tag", "v1.0")
+	repo.commit("Second", "2024-01-02T10:00:00Z")
+
+	// Without a reachable tag there is no description.
+	repo.git("checkout", "--detach", "HEAD~1")
+	repo.git("tag", "-d", "v1.0")
+
+	head, err = GetHead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !head.Detached() || !IsFullHash(head.Hash) || !strings.HasPrefix(head.Hash, head.ShortHash) || head.Description != "" {
+		t.Errorf("GetHead = %+v, want the first commit without a description", head)
+	}
+	if head.String() != head.ShortHash {
+		t.Errorf("String() = %q, want %q", head.String(), head.ShortHash)
+	}
+
+	repo.git("tag", "v1.0")
+	if err := CheckoutDetached(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err = GetHead(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1.0-1-g" + head.ShortHash; head.Description != want {
+		t.Errorf("Description = %q, want %q", head.Description, want)
+	}
+	if want := head.ShortHash + " (v1.0-1-g" + head.ShortHash + ")"; head.String() != want {
+		t.Errorf("String() = %q, want %q", head.String(), want)
+	}
+}
+
+func TestListTags(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.git("tag", "v1.0")
+	repo.commit("Second", "2024-01-02T10:00:00Z")
+	repo.git("tag", "-a", "v2.0", "-m", "Release 2.0")
+	t.Chdir(repo.dir)
+
+	tags, err := ListTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if want := []string{"v1.0", "v2.0"}; !slices.Equal(names, want) {
+		t.Fatalf("tags = %v, want %v", names, want)
+	}
+
+	// Annotated tags are described by the commit they point at.
+	v2 := tags[1]
+	if v2.Ref != "refs/tags/v2.0" || v2.Subject != "Second" || v2.Author != "Ada Lovelace" || !IsFullHash(v2.Hash) {
+		t.Errorf("v2.0 = %+v, want the second commit", v2)
+	}
+	if want := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC); !v2.CommitterDate.Equal(want) {
+		t.Errorf("v2.0 committed at %v, want %v", v2.CommitterDate, want)
+	}
+}
+
+func TestVisitedBranches(t *testing.T) {
+	const (
+		first  = "0123456789abcdef0123456789abcdef01234567"
+		second = "89abcdef0123456789abcdef0123456789abcdef"
+	)
+
+	// git records the full hash when leaving a detached HEAD.
+	entries := []CheckoutEntry{
+		{From: first, To: "main"},
+		{From: "feature", To: first},
+		{From: second, To: "feature"},
+		{From: "main", To: second},
+		{From: "0123456", To: "main"},
+	}
+
+	want := []string{first, "feature", second, "main"}
+	if got := VisitedBranches(entries); !slices.Equal(got, want) {
+		t.Errorf("VisitedBranches = %v, want %v", got, want)
+	}
+}
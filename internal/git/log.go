@@ -0,0 +1,94 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogOneline returns up to limit commits in `git log --oneline` format that
+// are reachable from ref but not from base. If base is empty or the same as
+// ref, the most recent commits on ref are returned instead.
+func LogOneline(ctx context.Context, base, ref string, limit int) ([]string, error) {
+	revisions := ref
+	if base != "" && base != ref {
+		revisions = base + ".." + ref
+	}
+
+	out, err := run(ctx, "log", "--oneline", "--no-decorate", "--no-color", "-n", fmt.Sprint(limit), revisions, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return []string{}, nil
+	}
+
+	return strings.Split(out, "\n"), nil
+}
+
+// AheadBehind counts how far two revisions have diverged.
+type AheadBehind struct {
+	// Ahead is the number of commits reachable from the revision but not
+	// from its base.
+	Ahead int
+	// Behind is the number of commits reachable from the base but not from
+	// the revision.
+	Behind int
+}
+
+// CountAheadBehind counts the commits ref is ahead of and behind base.
+func CountAheadBehind(ctx context.Context, ref, base string) (AheadBehind, error) {
+	out, err := run(ctx, "rev-list", "--left-right", "--count", ref+"..."+base, "--")
+	if err != nil {
+		return AheadBehind{}, err
+	}
+
+	var counts AheadBehind
+	if _, err := fmt.Sscan(out, &counts.Ahead, &counts.Behind); err != nil {
+		return AheadBehind{}, err
+	}
+
+	return counts, nil
+}
+
+// commitFormat is the log format used by ListCommits. Fields are separated by
+// NUL bytes so that commit subjects can contain anything.
+const commitFormat = "%H%x00%h%x00%ct%x00%an%x00%s"
+
+// ListCommits returns up to limit commits reachable from ref, most recent
+// first. Commits are returned as branches named after their abbreviated hash,
+// with the full hash as their ref, so that they can be listed like branches.
+func ListCommits(ctx context.Context, ref string, limit int) ([]Branch, error) {
+	out, err := run(ctx, "log", "--format="+commitFormat, "-n", fmt.Sprint(limit), ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []Branch{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 5 {
+			continue
+		}
+
+		commit := Branch{
+			Name:    fields[1],
+			Ref:     fields[0],
+			Hash:    fields[0],
+			Author:  fields[3],
+			Subject: fields[4],
+		}
+
+		if unix, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			commit.CommitterDate = time.Unix(unix, 0)
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
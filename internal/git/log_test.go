@@ -0,0 +1,76 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListCommits(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.commit("Second", "2024-01-02T10:00:00Z")
+	repo.commit("Third", "2024-01-03T10:00:00Z")
+	t.Chdir(repo.dir)
+
+	commits, err := ListCommits(context.Background(), "refs/heads/main", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commits) != 2 || commits[0].Subject != "Third" || commits[1].Subject != "Second" {
+		t.Fatalf("commits = %+v, want the last two, most recent first", commits)
+	}
+
+	commit := commits[0]
+	if !IsFullHash(commit.Hash) || commit.Ref != commit.Hash || !strings.HasPrefix(commit.Hash, commit.Name) {
+		t.Errorf("commit = %+v, want it named after its abbreviated hash", commit)
+	}
+	if commit.Author != "Ada Lovelace" {
+		t.Errorf("author = %q, want %q", commit.Author, "Ada Lovelace")
+	}
+	if want := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC); !commit.CommitterDate.Equal(want) {
+		t.Errorf("committed at %v, want %v", commit.CommitterDate, want)
+	}
+}
+
+func TestCountAheadBehind(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	repo.git("checkout", "-b", "feature")
+	repo.commit("Feature one", "2024-01-02T10:00:00Z")
+	repo.commit("Feature two", "2024-01-03T10:00:00Z")
+	repo.git("checkout", "main")
+	repo.commit("Main one", "2024-01-04T10:00:00Z")
+	repo.commit("Main two", "2024-01-05T10:00:00Z")
+	repo.commit("Main three", "2024-01-06T10:00:00Z")
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	tests := []struct {
+		ref, base string
+		want      AheadBehind
+	}{
+		{"refs/heads/feature", "main", AheadBehind{Ahead: 2, Behind: 3}},
+		{"refs/heads/main", "feature", AheadBehind{Ahead: 3, Behind: 2}},
+		{"refs/heads/main", "main", AheadBehind{}},
+		{"refs/heads/main~3", "main", AheadBehind{Behind: 3}},
+	}
+
+	for _, tt := range tests {
+		got, err := CountAheadBehind(ctx, tt.ref, tt.base)
+		if err != nil {
+			t.Fatalf("CountAheadBehind(%q, %q): %v", tt.ref, tt.base, err)
+		}
+		if got != tt.want {
+			t.Errorf("CountAheadBehind(%q, %q) = %+v, want %+v", tt.ref, tt.base, got, tt.want)
+		}
+	}
+
+	if _, err := CountAheadBehind(ctx, "refs/heads/missing", "main"); err == nil {
+		t.Error("CountAheadBehind succeeded for a missing branch")
+	}
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"context"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// CheckoutEntry is a single checkout recorded in the HEAD reflog.
+type CheckoutEntry struct {
+	// From is the branch or commit that was checked out before.
+	From string
+	// To is the branch or commit that was checked out.
+	To string
+}
+
+const checkoutReflogPrefix = "checkout: moving from "
+
+// ListCheckouts returns every checkout recorded in the HEAD reflog, most
+// recent first.
+func ListCheckouts(ctx context.Context) ([]CheckoutEntry, error) {
+	out, err := run(ctx, "reflog", "--format=%gs")
+	if err != nil {
+		// A repository without any commits has no reflog yet.
+		return []CheckoutEntry{}, nil
+	}
+
+	return lo.FilterMap(strings.Split(out, "\n"), func(line string, _ int) (CheckoutEntry, bool) {
+		return parseCheckout(line)
+	}), nil
+}
+
+// parseCheckout parses the message of a reflog entry, returning false if the
+// entry isn't for a checkout or is malformed.
+func parseCheckout(message string) (CheckoutEntry, bool) {
+	if !strings.HasPrefix(message, checkoutReflogPrefix) {
+		return CheckoutEntry{}, false
+	}
+
+	from, to, found := strings.Cut(strings.TrimPrefix(message, checkoutReflogPrefix), " to ")
+	if !found || from == "" || to == "" {
+		return CheckoutEntry{}, false
+	}
+
+	return CheckoutEntry{From: from, To: to}, true
+}
+
+// RecentlyCheckedOut returns the names of the branches checked out in
+// entries, most recently checked out first.
+func RecentlyCheckedOut(entries []CheckoutEntry) []string {
+	return lo.Uniq(lo.Map(entries, func(e CheckoutEntry, _ int) string {
+		return e.To
+	}))
+}
+
+// VisitedBranches returns the branches that were checked out before the
+// current one, most recently left first, according to entries. Unlike the
+// history kept by git-switch, this includes branches switched away from using
+// git directly. The reflog records the full hash of the commit HEAD was
+// detached at when leaving it, so detached commits are included by their full
+// hash. Anything else that looks like a hash is left out.
+func VisitedBranches(entries []CheckoutEntry) []string {
+	return lo.Uniq(lo.FilterMap(entries, func(e CheckoutEntry, _ int) (string, bool) {
+		return e.From, IsFullHash(e.From) || !isCommitHash(e.From)
+	}))
+}
+
+// isCommitHash reports whether s looks like an abbreviated or full commit
+// hash, which is what the reflog records when HEAD was detached.
+func isCommitHash(s string) bool {
+	if len(s) < 7 || len(s) > 64 {
+		return false
+	}
+
+	return strings.Trim(strings.ToLower(s), "0123456789abcdef") == ""
+}
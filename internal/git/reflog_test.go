@@ -0,0 +1,39 @@
+package git
+
+import "testing"
+
+func TestParseCheckout(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		message string
+		want    CheckoutEntry
+		ok      bool
+	}{
+		{"checkout: moving from main to feature/login", CheckoutEntry{From: "main", To: "feature/login"}, true},
+		{"checkout: moving from " + commit + " to main", CheckoutEntry{From: commit, To: "main"}, true},
+		{"checkout: moving from main to v1.0", CheckoutEntry{From: "main", To: "v1.0"}, true},
+
+		// Entries for anything but a checkout.
+		{"commit: Add a feature", CheckoutEntry{}, false},
+		{"commit (initial): First", CheckoutEntry{}, false},
+		{"reset: moving to HEAD~1", CheckoutEntry{}, false},
+		{"rebase (finish): returning to refs/heads/main", CheckoutEntry{}, false},
+		{"merge feature: Fast-forward", CheckoutEntry{}, false},
+		{"pull: checkout: moving from main to feature", CheckoutEntry{}, false},
+
+		// Malformed checkouts.
+		{"", CheckoutEntry{}, false},
+		{"checkout: moving from main", CheckoutEntry{}, false},
+		{"checkout: moving from main to ", CheckoutEntry{}, false},
+		{"checkout: moving from  to main", CheckoutEntry{}, false},
+		{"checkout:moving from main to feature", CheckoutEntry{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseCheckout(tt.message)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseCheckout(%q) = %+v, %v, want %+v, %v", tt.message, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package git
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
-func ListRemotes() ([]string, error) {
-	out, err := executeHide("remote")
+func ListRemotes(ctx context.Context) ([]string, error) {
+	out, err := run(ctx, "remote")
 	if err != nil {
-		print(out)
 		return nil, err
 	}
 
-	return strings.Split(out, "\n"), nil
+	return strings.Fields(out), nil
 }
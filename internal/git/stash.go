@@ -0,0 +1,71 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var (
+	ErrStashNotFound = errors.New("stash not found")
+)
+
+// HasLocalChanges reports whether tracked files have changes that aren't
+// committed, staged or not. Untracked files are ignored.
+func HasLocalChanges(ctx context.Context) (bool, error) {
+	out, err := run(ctx, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(out) != "", nil
+}
+
+// StashPush stashes the changes to tracked files with message, and returns the
+// hash of the stash commit. The hash is empty when there was nothing to stash.
+func StashPush(ctx context.Context, message string) (string, error) {
+	before := stashHash(ctx)
+
+	if _, err := run(ctx, "stash", "push", "--message", message); err != nil {
+		return "", err
+	}
+
+	after := stashHash(ctx)
+	if after == before {
+		return "", nil
+	}
+
+	return after, nil
+}
+
+// StashPop applies the stash with the given commit hash and drops it. It
+// returns ErrStashNotFound if there is no such stash, for example because it
+// was dropped by hand.
+func StashPop(ctx context.Context, hash string) error {
+	out, err := run(ctx, "stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+
+	idx := lo.IndexOf(strings.Split(strings.TrimSpace(out), "\n"), hash)
+	if hash == "" || idx == -1 {
+		return ErrStashNotFound
+	}
+
+	_, err = run(ctx, "stash", "pop", fmt.Sprintf("stash@{%v}", idx))
+	return err
+}
+
+// stashHash returns the hash of the most recent stash, or an empty string if
+// there are no stashes.
+func stashHash(ctx context.Context) string {
+	out, err := run(ctx, "rev-parse", "--quiet", "--verify", "refs/stash")
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(out)
+}
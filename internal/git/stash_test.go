@@ -0,0 +1,80 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestStash(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+	if err := os.WriteFile(repo.path("file"), []byte("committed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo.git("add", "file")
+	repo.commit("First", "2024-01-01T10:00:00Z")
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(repo.path("file"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertLocalChanges := func(want bool) {
+		t.Helper()
+		got, err := HasLocalChanges(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("HasLocalChanges = %v, want %v", got, want)
+		}
+	}
+
+	// Untracked files aren't local changes.
+	if err := os.WriteFile(repo.path("untracked"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertLocalChanges(false)
+
+	hash, err := StashPush(ctx, "nothing")
+	if err != nil || hash != "" {
+		t.Errorf("StashPush without changes = %q, %v, want nothing stashed", hash, err)
+	}
+
+	write("first\n")
+	assertLocalChanges(true)
+
+	first, err := StashPush(ctx, "first")
+	if err != nil || first == "" {
+		t.Fatalf("StashPush = %q, %v, want a stash", first, err)
+	}
+	assertLocalChanges(false)
+
+	write("second\n")
+	if _, err := StashPush(ctx, "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first stash is no longer the most recent one.
+	if err := StashPop(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(repo.path("file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "first\n" {
+		t.Errorf("file contains %q after popping the first stash, want %q", content, "first\n")
+	}
+
+	if err := StashPop(ctx, first); !errors.Is(err, ErrStashNotFound) {
+		t.Errorf("popping a dropped stash = %v, want %v", err, ErrStashNotFound)
+	}
+}
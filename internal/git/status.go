@@ -0,0 +1,117 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Operations that can be in progress in a working tree.
+const (
+	OperationMerge      = "merge"
+	OperationRebase     = "rebase"
+	OperationCherryPick = "cherry-pick"
+	OperationRevert     = "revert"
+	OperationBisect     = "bisect"
+)
+
+// Status describes the state of the working tree.
+type Status struct {
+	// Staged is the number of files with staged changes.
+	Staged int
+	// Unstaged is the number of tracked files with changes that aren't
+	// staged.
+	Unstaged int
+	// Untracked is the number of untracked files.
+	Untracked int
+	// Conflicted is the number of files with merge conflicts.
+	Conflicted int
+	// Operation is the operation in progress, such as OperationRebase, or
+	// empty if there is none.
+	Operation string
+}
+
+// HasLocalChanges reports whether tracked files have uncommitted changes, like
+// the function of the same name does.
+func (s Status) HasLocalChanges() bool {
+	return s.Staged+s.Unstaged+s.Conflicted > 0
+}
+
+// GetStatus returns the status of the working tree.
+func GetStatus(ctx context.Context) (Status, error) {
+	gitDir, err := run(ctx, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return Status{}, err
+	}
+
+	return getStatus(ctx, strings.TrimSpace(gitDir))
+}
+
+// getStatus returns the status of the working tree whose git directory is
+// gitDir.
+func getStatus(ctx context.Context, gitDir string) (Status, error) {
+	out, err := run(ctx, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := parseStatus(out)
+	status.Operation = operationInProgress(gitDir)
+	return status, nil
+}
+
+// parseStatus counts the changes listed in the output of git status
+// --porcelain=v2.
+func parseStatus(out string) Status {
+	var status Status
+	for _, line := range strings.Split(out, "\n") {
+		kind, rest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "1", "2":
+			// Ordinary and renamed or copied entries start with a staged
+			// and an unstaged status, where . means unchanged.
+			if len(rest) < 2 {
+				continue
+			}
+			if rest[0] != '.' {
+				status.Staged++
+			}
+			if rest[1] != '.' {
+				status.Unstaged++
+			}
+		case "u":
+			status.Conflicted++
+		case "?":
+			status.Untracked++
+		}
+	}
+
+	return status
+}
+
+// operationInProgress returns the operation in progress according to the
+// state files git keeps in gitDir, or an empty string if there is none.
+func operationInProgress(gitDir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	// A rebase stopped on a conflicting commit can also have a cherry-pick
+	// head, so rebases are looked for first.
+	switch {
+	case exists("rebase-merge"), exists("rebase-apply"):
+		return OperationRebase
+	case exists("MERGE_HEAD"):
+		return OperationMerge
+	case exists("CHERRY_PICK_HEAD"):
+		return OperationCherryPick
+	case exists("REVERT_HEAD"):
+		return OperationRevert
+	case exists("BISECT_LOG"):
+		return OperationBisect
+	}
+
+	return ""
+}
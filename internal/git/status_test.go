@@ -0,0 +1,106 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	out := "# branch.oid 1111111111111111111111111111111111111111\n" +
+		"# branch.head main\n" +
+		"# branch.upstream origin/main\n" +
+		"# branch.ab +1 -2\n" +
+		"1 M. N... 100644 100644 100644 aaaa bbbb staged.txt\n" +
+		"1 .M N... 100644 100644 100644 aaaa aaaa unstaged.txt\n" +
+		"1 MM N... 100644 100644 100644 aaaa bbbb both.txt\n" +
+		"2 R. N... 100644 100644 100644 aaaa aaaa R100 new.txt\told.txt\n" +
+		"u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.txt\n" +
+		"? untracked.txt\n" +
+		"? other.txt\n" +
+		"! ignored.txt\n"
+
+	want := Status{Staged: 3, Unstaged: 2, Untracked: 2, Conflicted: 1}
+	if got := parseStatus(out); got != want {
+		t.Errorf("parseStatus = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	repo := newFixture(t)
+	repo.git("init", "-b", "main")
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(repo.path(name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("file", "base\n")
+	repo.git("add", "file")
+	repo.commit("Base", "2024-01-01T10:00:00Z")
+	repo.git("checkout", "-b", "other")
+	write("file", "other\n")
+	repo.git("commit", "-am", "Other")
+	repo.git("checkout", "main")
+	write("file", "main\n")
+	repo.git("commit", "-am", "Main")
+
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	status, err := GetStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != (Status{}) {
+		t.Errorf("status of a clean working tree = %+v, want nothing", status)
+	}
+
+	// git exits with an error when the merge conflicts.
+	cmd := repo.command("merge", "other")
+	_ = cmd.Run()
+	write("untracked", "")
+
+	status, err = GetStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Status{Conflicted: 1, Untracked: 1, Operation: OperationMerge}
+	if status != want {
+		t.Errorf("status during a conflicting merge = %+v, want %+v", status, want)
+	}
+
+	repo.git("merge", "--abort")
+	repo.git("bisect", "start")
+
+	status, err = GetStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Operation != OperationBisect {
+		t.Errorf("operation during a bisect = %q, want %q", status.Operation, OperationBisect)
+	}
+}
+
+func TestStatusHasLocalChanges(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   bool
+	}{
+		{Status{}, false},
+		{Status{Untracked: 2, Operation: OperationBisect}, false},
+		{Status{Staged: 1}, true},
+		{Status{Unstaged: 1}, true},
+		{Status{Conflicted: 1}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.HasLocalChanges(); got != tt.want {
+			t.Errorf("%+v.HasLocalChanges() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
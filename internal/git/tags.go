@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tagFormat is the for-each-ref format used by ListTags. The fields starting
+// with * describe the commit an annotated tag points at, and are empty for
+// lightweight tags.
+const tagFormat = "%(refname)%00%(refname:short)%00%(objectname)%00%(*objectname)%00%(committerdate:unix)%00%(*committerdate:unix)%00%(authorname)%00%(*authorname)%00%(contents:subject)%00%(*contents:subject)"
+
+// ListTags returns the tags of the repository as branches, so that they can
+// be listed alongside them. Annotated tags are described by the commit they
+// point at rather than by the tag itself. Tags that don't point at a commit
+// are left out.
+func ListTags(ctx context.Context) ([]Branch, error) {
+	out, err := run(ctx, "for-each-ref", "--format="+tagFormat, "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []Branch{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 10 {
+			continue
+		}
+
+		// Use the fields for the commit an annotated tag points at.
+		hash, date, author, subject := fields[2], fields[4], fields[6], fields[8]
+		if fields[3] != "" {
+			hash, date, author, subject = fields[3], fields[5], fields[7], fields[9]
+		}
+
+		// Only commits have a committer date.
+		unix, err := strconv.ParseInt(date, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		tags = append(tags, Branch{
+			Name:          fields[1],
+			Ref:           fields[0],
+			Hash:          hash,
+			CommitterDate: time.Unix(unix, 0),
+			Author:        author,
+			Subject:       subject,
+		})
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"context"
+	"strings"
+)
+
+// Worktree describes a working tree attached to the repository.
+type Worktree struct {
+	// Path is the path to the root of the working tree.
+	Path string
+	// Hash is the hash of the commit checked out. It is empty for bare
+	// repositories, and the zero hash for branches without commits.
+	Hash string
+	// Branch is the short name of the branch checked out, empty when HEAD
+	// is detached.
+	Branch string
+	// Bare is true for the main worktree of a bare repository.
+	Bare bool
+	// Detached is true when HEAD is detached.
+	Detached bool
+}
+
+// ListWorktrees lists the worktrees of the repository, the main worktree
+// first.
+func ListWorktrees(ctx context.Context) ([]Worktree, error) {
+	out, err := run(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWorktrees(out), nil
+}
+
+// parseWorktrees parses the output of git worktree list --porcelain, which
+// has an attribute per line and a blank line after each worktree.
+func parseWorktrees(out string) []Worktree {
+	worktrees := []Worktree{}
+	for _, record := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n\n") {
+		var worktree Worktree
+		for _, attribute := range strings.Split(record, "\n") {
+			key, value, _ := strings.Cut(attribute, " ")
+			switch key {
+			case "worktree":
+				worktree.Path = value
+			case "HEAD":
+				worktree.Hash = value
+			case "branch":
+				worktree.Branch = strings.TrimPrefix(value, "refs/heads/")
+			case "bare":
+				worktree.Bare = true
+			case "detached":
+				worktree.Detached = true
+			}
+		}
+
+		if worktree.Path != "" {
+			worktrees = append(worktrees, worktree)
+		}
+	}
+
+	return worktrees
+}
+
+// AddWorktree creates a worktree at path with branch checked out. When remote
+// is set, branch is created tracking the branch of the same name on remote.
+func AddWorktree(ctx context.Context, path, remote, branch string) error {
+	if remote != "" {
+		_, err := run(ctx, "worktree", "add", "--track", "-b", branch, path, remote+"/"+branch)
+		return err
+	}
+
+	_, err := run(ctx, "worktree", "add", path, branch)
+	return err
+}
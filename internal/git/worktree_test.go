@@ -0,0 +1,97 @@
+package git
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseWorktrees(t *testing.T) {
+	out := "worktree /src/repo\n" +
+		"HEAD 1111111111111111111111111111111111111111\n" +
+		"branch refs/heads/main\n" +
+		"\n" +
+		"worktree /src/repo-feature/login\n" +
+		"HEAD 2222222222222222222222222222222222222222\n" +
+		"branch refs/heads/feature/login\n" +
+		"locked reason given\n" +
+		"\n" +
+		"worktree /src/repo-detached\n" +
+		"HEAD 3333333333333333333333333333333333333333\n" +
+		"detached\n" +
+		"prunable gitdir file points to non-existent location\n" +
+		"\n"
+
+	want := []Worktree{
+		{Path: "/src/repo", Hash: "1111111111111111111111111111111111111111", Branch: "main"},
+		{Path: "/src/repo-feature/login", Hash: "2222222222222222222222222222222222222222", Branch: "feature/login"},
+		{Path: "/src/repo-detached", Hash: "3333333333333333333333333333333333333333", Detached: true},
+	}
+
+	if got := parseWorktrees(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWorktrees = %+v, want %+v", got, want)
+	}
+
+	bare := parseWorktrees("worktree /src/repo.git\nbare\n\n")
+	if len(bare) != 1 || !bare[0].Bare {
+		t.Errorf("parseWorktrees of a bare repository = %+v, want one bare worktree", bare)
+	}
+}
+
+func TestAddWorktree(t *testing.T) {
+	origin := newFixture(t)
+	origin.git("init", "-b", "main")
+	origin.commit("First", "2024-01-01T10:00:00Z")
+	origin.git("branch", "remote-only")
+
+	repo := &fixture{t: t, dir: t.TempDir()}
+	repo.git("clone", origin.dir, ".")
+	repo.git("branch", "feature")
+	t.Chdir(repo.dir)
+	ctx := context.Background()
+
+	if err := AddWorktree(ctx, repo.path("feature"), "", "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddWorktree(ctx, repo.path("tracking"), "origin", "remote-only"); err != nil {
+		t.Fatal(err)
+	}
+
+	worktrees, err := ListWorktrees(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branches := map[string]string{}
+	for _, worktree := range worktrees {
+		path, err := filepath.EvalSymlinks(worktree.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		branches[worktree.Branch] = path
+	}
+
+	for branch, dir := range map[string]string{"main": "", "feature": "feature", "remote-only": "tracking"} {
+		want, err := filepath.EvalSymlinks(repo.path(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if branches[branch] != want {
+			t.Errorf("%v is checked out in %q, want %q", branch, branches[branch], want)
+		}
+	}
+
+	local, err := ListBranches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, branch := range local {
+		if branch.Name == "remote-only" && branch.Upstream != "origin/remote-only" {
+			t.Errorf("remote-only tracks %q, want origin/remote-only", branch.Upstream)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+// isHidden reports whether the named branch is hidden. Tags and commits are
+// never hidden.
+func (r *Renderer) isHidden(name string) bool {
+	return r.listingBranches() && lo.Contains(r.state.Hidden, name)
+}
+
+// hideBranch hides the named branch, or lists it again if it was hidden and
+// hidden branches are being shown.
+func (r *Renderer) hideBranch(handler SelectionHandler, name string) {
+	if name == r.cfg.CurrentBranch {
+		r.state.Message = fmt.Sprintf("can't hide %v, it is checked out", name)
+		return
+	}
+
+	if r.isHidden(name) {
+		if handler.OnUnhide == nil {
+			return
+		}
+
+		if err := handler.OnUnhide(name); err != nil {
+			r.state.Message = fmt.Sprintf("error: %v", err)
+			return
+		}
+
+		r.state.Hidden = lo.Without(r.state.Hidden, name)
+		r.refreshBranchListWithSelection(name, true)
+		return
+	}
+
+	if handler.OnHide == nil {
+		return
+	}
+
+	if err := handler.OnHide(name); err != nil {
+		r.state.Message = fmt.Sprintf("error: %v", err)
+		return
+	}
+
+	r.state.Hidden = append(r.state.Hidden, name)
+	if r.state.ShowHidden {
+		r.refreshBranchListWithSelection(name, true)
+		return
+	}
+
+	r.state.Message = fmt.Sprintf("hid %v", name)
+	r.refreshBranchListWithSelection(r.neighbourOf(name), false)
+}
+
+// toggleHidden toggles whether hidden branches are listed, keeping the
+// selected branch selected.
+func (r *Renderer) toggleHidden() {
+	selected, _ := r.selectedBranch()
+	r.state.ShowHidden = !r.state.ShowHidden
+	r.refreshBranchListWithSelection(selected, true)
+}
+
+// neighbourOf returns the branch listed after the named branch, or the one
+// before it if it is the last, so that the selection can stay in place when
+// the branch is removed from the list.
+func (r *Renderer) neighbourOf(name string) string {
+	idx := lo.IndexOf(r.state.Branches, name)
+	switch {
+	case idx == -1:
+		return ""
+	case idx+1 < len(r.state.Branches):
+		return r.state.Branches[idx+1]
+	case idx > 0:
+		return r.state.Branches[idx-1]
+	}
+
+	return ""
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"slices"
+	"sync"
+)
+
+// maxConcurrentLoads limits how many values an asyncCache loads at once.
+// Values scrolled past before a load was free for them are never loaded, so
+// scrolling quickly through a long list doesn't spawn a git process for every
+// branch scrolled past.
+const maxConcurrentLoads = 4
+
+// asyncResult is a value loaded by an asyncCache.
+type asyncResult[V any] struct {
+	value V
+	err   error
+}
+
+// pendingLoad is a value waiting to be loaded by an asyncCache.
+type pendingLoad[K comparable, V any] struct {
+	key  K
+	load func() (V, error)
+	// frame is the frame the value was last requested in.
+	frame int
+}
+
+// asyncCache loads values in the background so that the UI never blocks on
+// git. notify is called whenever a value finishes loading so that the screen
+// can be redrawn.
+//
+// The most recently requested values are loaded first, and values that
+// weren't requested while drawing the last frame are dropped before they
+// start loading, since they have scrolled out of view. See nextFrame.
+type asyncCache[K comparable, V any] struct {
+	mu     sync.Mutex
+	values map[K]asyncResult[V]
+	// pending holds the values waiting to be loaded, the most recently
+	// requested last.
+	pending []pendingLoad[K, V]
+	// loading holds the keys of the values being loaded.
+	loading map[K]bool
+	// workers is the number of goroutines loading values.
+	workers int
+	frame   int
+	notify  func()
+}
+
+func newAsyncCache[K comparable, V any](notify func()) *asyncCache[K, V] {
+	return &asyncCache[K, V]{
+		values:  map[K]asyncResult[V]{},
+		loading: map[K]bool{},
+		notify:  notify,
+	}
+}
+
+// get returns the value for key if it has finished loading. Otherwise it
+// queues the value to be loaded in the background using load, unless it is
+// already being loaded, and returns false.
+func (c *asyncCache[K, V]) get(key K, load func() (V, error)) (asyncResult[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if result, ok := c.values[key]; ok {
+		return result, true
+	}
+
+	if c.loading[key] {
+		return asyncResult[V]{}, false
+	}
+
+	// Move the value to the front of the queue if it is already waiting.
+	c.pending = slices.DeleteFunc(c.pending, func(p pendingLoad[K, V]) bool {
+		return p.key == key
+	})
+	c.pending = append(c.pending, pendingLoad[K, V]{key: key, load: load, frame: c.frame})
+
+	if c.workers < maxConcurrentLoads {
+		c.workers++
+		go c.work()
+	}
+
+	return asyncResult[V]{}, false
+}
+
+// work loads pending values, most recently requested first, until there are
+// none left.
+func (c *asyncCache[K, V]) work() {
+	for {
+		c.mu.Lock()
+		if len(c.pending) == 0 {
+			c.workers--
+			c.mu.Unlock()
+			return
+		}
+
+		next := c.pending[len(c.pending)-1]
+		c.pending = c.pending[:len(c.pending)-1]
+		c.loading[next.key] = true
+		c.mu.Unlock()
+
+		value, err := next.load()
+
+		c.mu.Lock()
+		delete(c.loading, next.key)
+		c.values[next.key] = asyncResult[V]{value: value, err: err}
+		c.mu.Unlock()
+
+		if c.notify != nil {
+			c.notify()
+		}
+	}
+}
+
+// nextFrame is called before drawing a frame. Values that weren't requested
+// while drawing the previous frame are no longer on screen, so they are
+// dropped from the queue and only loaded if they are requested again.
+func (c *asyncCache[K, V]) nextFrame() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = slices.DeleteFunc(c.pending, func(p pendingLoad[K, V]) bool {
+		return p.frame < c.frame
+	})
+	c.frame++
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// idle reports whether the cache has nothing left to load.
+func (c *asyncCache[K, V]) idle() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.workers == 0
+}
+
+// waitIdle waits for c to have nothing left to load.
+func waitIdle[K comparable, V any](t *testing.T, c *asyncCache[K, V]) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.idle() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for values to load")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// loadRecorder records the order values are loaded in. Loads block until
+// release is called.
+type loadRecorder struct {
+	mu      sync.Mutex
+	loaded  []string
+	release chan struct{}
+}
+
+func newLoadRecorder() *loadRecorder {
+	return &loadRecorder{release: make(chan struct{})}
+}
+
+func (l *loadRecorder) load(key string) func() (string, error) {
+	return func() (string, error) {
+		<-l.release
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.loaded = append(l.loaded, key)
+		return "value of " + key, nil
+	}
+}
+
+// occupy starts as many loads as the cache runs at once, so that the values
+// requested next have to wait.
+func (l *loadRecorder) occupy(c *asyncCache[string, string]) {
+	for i := range maxConcurrentLoads {
+		c.get(string(rune('0'+i)), l.load("busy"))
+	}
+
+	// Wait for the workers to pick the loads up.
+	for {
+		c.mu.Lock()
+		waiting := len(c.pending)
+		c.mu.Unlock()
+		if waiting == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// requested returns the values loaded other than those started by occupy.
+func (l *loadRecorder) requested() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.DeleteFunc(slices.Clone(l.loaded), func(key string) bool {
+		return key == "busy"
+	})
+}
+
+func TestAsyncCacheLoadsOnce(t *testing.T) {
+	notified := make(chan struct{}, 1)
+	c := newAsyncCache[string, string](func() { notified <- struct{}{} })
+
+	loads := 0
+	load := func() (string, error) {
+		loads++
+		return "value", nil
+	}
+
+	if _, ok := c.get("key", load); ok {
+		t.Fatal("get returned a value before it was loaded")
+	}
+	<-notified
+	waitIdle(t, c)
+
+	for range 2 {
+		result, ok := c.get("key", load)
+		if !ok || result.value != "value" || result.err != nil {
+			t.Errorf("get = %+v, %v, want the loaded value", result, ok)
+		}
+	}
+
+	if loads != 1 {
+		t.Errorf("loaded %v times, want once", loads)
+	}
+}
+
+func TestAsyncCacheLoadsNewestFirst(t *testing.T) {
+	c := newAsyncCache[string, string](nil)
+	l := newLoadRecorder()
+	l.occupy(c)
+
+	c.get("a", l.load("a"))
+	c.get("b", l.load("b"))
+	c.get("c", l.load("c"))
+	// Requesting a again moves it ahead of the others.
+	c.get("a", l.load("a"))
+
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.pending))
+	for _, p := range c.pending {
+		keys = append(keys, p.key)
+	}
+	c.mu.Unlock()
+
+	// The next value loaded is the last one queued.
+	if want := []string{"b", "c", "a"}; !slices.Equal(keys, want) {
+		t.Errorf("queue = %v, want %v", keys, want)
+	}
+
+	close(l.release)
+	waitIdle(t, c)
+}
+
+func TestAsyncCacheDropsStaleLoads(t *testing.T) {
+	c := newAsyncCache[string, string](nil)
+	l := newLoadRecorder()
+	l.occupy(c)
+
+	// a scrolls out of view after the first frame, while b stays in view.
+	c.get("a", l.load("a"))
+	c.get("b", l.load("b"))
+	c.nextFrame()
+	c.get("b", l.load("b"))
+	c.nextFrame()
+
+	close(l.release)
+	waitIdle(t, c)
+
+	if got, want := l.requested(), []string{"b"}; !slices.Equal(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+
+	// Dropped values are loaded if they are requested again.
+	c.get("a", l.load("a"))
+	waitIdle(t, c)
+
+	if result, ok := c.get("a", l.load("a")); !ok || result.value != "value of a" {
+		t.Errorf("get(a) = %+v, %v after requesting it again, want its value", result, ok)
+	}
+}
@@ -0,0 +1,96 @@
+// Package pattern matches branch names against the patterns users put in the
+// config.
+//
+// A pattern is either a glob, where * matches any characters including /,
+// ? matches a single character and [...] matches a character class, or a
+// regular expression prefixed with "re:". Globs must match the whole branch
+// name, while regular expressions match anywhere in it unless anchored with ^
+// or $.
+package pattern
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexpPrefix marks a pattern as a regular expression rather than a glob.
+const regexpPrefix = "re:"
+
+// List is a list of compiled patterns.
+type List []*regexp.Regexp
+
+// IsPattern reports whether s is a pattern rather than a branch name. Branch
+// names can't contain *, ?, [, \ or :, so anything containing them is a
+// pattern.
+func IsPattern(s string) bool {
+	return strings.HasPrefix(s, regexpPrefix) || strings.ContainsAny(s, `*?[\`)
+}
+
+// Compile compiles patterns into a List.
+func Compile(patterns []string) (List, error) {
+	list := make(List, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		list = append(list, re)
+	}
+
+	return list, nil
+}
+
+// Match reports whether name matches any of the patterns in the list.
+func (l List) Match(name string) bool {
+	for _, re := range l {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compile(p string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(p, regexpPrefix); ok {
+		return regexp.Compile(expr)
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	runes := []rune(p)
+	for i := 0; i < len(runes); i++ {
+		switch ch := runes[i]; ch {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			// Copy the character class as is, turning a leading ! into ^ as
+			// in shell globs.
+			end := strings.IndexRune(string(runes[i+1:]), ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := []rune(string(runes[i+1:])[:end])
+			if len(class) > 0 && class[0] == '!' {
+				class[0] = '^'
+			}
+			expr.WriteString("[" + string(class) + "]")
+			i += len(class) + 1
+		case '\\':
+			// Escape the next character.
+			if i+1 < len(runes) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
@@ -0,0 +1,66 @@
+package pattern
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"main", "main", true},
+		{"main", "main2", false},
+		{"dependabot/*", "dependabot/npm_and_yarn/lodash-4.17.21", true},
+		{"dependabot/*", "fix/dependabot", false},
+		{"release/v?", "release/v1", true},
+		{"release/v?", "release/v10", false},
+		{"release/[0-9]*", "release/2019", true},
+		{"release/[!0-9]*", "release/2019", false},
+		{"feature.x", "featureXx", false},
+		{`literal\*`, "literal*", true},
+		{`re:release/20(1\d|20)`, "release/2019", true},
+		{`re:release/20(1\d|20)`, "release/2021", false},
+		{"re:fix", "bugfix", true},
+		{"re:^fix", "bugfix", false},
+		{`re:^release/v[0-3]\.`, "release/v1.2", true},
+		{`re:^release/v[0-3]\.`, "release/v4.0", false},
+		{`re:^release/v[0-3]\.`, "old/release/v1.2", false},
+	}
+
+	for _, tt := range tests {
+		list, err := Compile([]string{tt.pattern})
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.pattern, err)
+		}
+
+		if got := list.Match(tt.name); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, p := range []string{"re:(", "release/[0-9"} {
+		if _, err := Compile([]string{p}); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", p)
+		}
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	tests := map[string]bool{
+		"main":          false,
+		"feature/login": false,
+		"release/*":     true,
+		"fix-?":         true,
+		"v[0-9]":        true,
+		`literal\*`:     true,
+		`re:^hotfix/\d`: true,
+	}
+
+	for s, want := range tests {
+		if got := IsPattern(s); got != want {
+			t.Errorf("IsPattern(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
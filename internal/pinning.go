@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/pattern"
+	"github.com/samber/lo"
+)
+
+// Quick select modes, choosing how numbered pinned branches are selected.
+const (
+	// QuickSelectAlt selects a numbered pinned branch with Alt and its
+	// number.
+	QuickSelectAlt = "alt"
+	// QuickSelectDigit also selects a numbered pinned branch by typing its
+	// number while the search is empty.
+	QuickSelectDigit = "digit"
+)
+
+// quickSelectCount is how many pinned branches are numbered.
+const quickSelectCount = 9
+
+// PinnedBranches returns the pinned branches among names, in the order they
+// were pinned. A pinned pattern pins every branch matching it that isn't
+// already pinned, in alphabetical order. Pins that aren't real branches and
+// patterns that don't compile are left out.
+func PinnedBranches(pins []string, names []string) []string {
+	result := []string{}
+	for _, pin := range pins {
+		if !pattern.IsPattern(pin) {
+			if lo.Contains(names, pin) {
+				result = append(result, pin)
+			}
+			continue
+		}
+
+		list, err := pattern.Compile([]string{pin})
+		if err != nil {
+			continue
+		}
+
+		matched := lo.Filter(names, func(name string, _ int) bool {
+			return list.Match(name) && !lo.Contains(pins, name)
+		})
+		slices.Sort(matched)
+		result = append(result, matched...)
+	}
+
+	return lo.Uniq(result)
+}
+
+// pinnedBranches returns the pinned branches among names. See PinnedBranches.
+func (r *Renderer) pinnedBranches(names []string) []string {
+	return PinnedBranches(*r.cfg.PinnedBranches, names)
+}
+
+// numberedBranches returns the pinned branches that can be quick selected
+// by their number, the first being number 1. Numbers don't change as the
+// search input changes. Nothing is numbered while tags or commits are
+// listed.
+func (r *Renderer) numberedBranches() []string {
+	if !r.listingBranches() {
+		return nil
+	}
+
+	names := lo.Map(r.cfg.Branches, func(b git.Branch, _ int) string {
+		return b.Name
+	})
+	pinned := r.pinnedBranches(names)
+
+	return pinned[:min(len(pinned), quickSelectCount)]
+}
+
+// quickSelect selects the pinned branch numbered n.
+func (r *Renderer) quickSelect(handler SelectionHandler, n int) {
+	numbered := r.numberedBranches()
+	if n < 1 || n > len(numbered) {
+		r.state.Message = fmt.Sprintf("no pinned branch numbered %v", n)
+		return
+	}
+
+	r.selectBranch(handler, numbered[n-1])
+}
+
+// quickSelectNumber returns the number of the pinned branch ev selects, if
+// any. Bare digits only select a pinned branch when there is one with that
+// number, and are typed into the search otherwise.
+func (r *Renderer) quickSelectNumber(ev *tcell.EventKey) (int, bool) {
+	if ev.Key() != tcell.KeyRune || ev.Rune() < '1' || ev.Rune() > '9' || !r.listingBranches() {
+		return 0, false
+	}
+
+	n := int(ev.Rune() - '0')
+	alt := ev.Modifiers()&tcell.ModAlt != 0
+	digit := r.cfg.QuickSelect == QuickSelectDigit && r.state.Input == "" && ev.Modifiers() == tcell.ModNone &&
+		n <= len(r.numberedBranches())
+	if !alt && !digit {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// pinningPattern returns the first pinned pattern matching name, if name is
+// only pinned because of a pattern.
+func (r *Renderer) pinningPattern(name string) (string, bool) {
+	if lo.Contains(*r.cfg.PinnedBranches, name) {
+		return "", false
+	}
+
+	return lo.Find(*r.cfg.PinnedBranches, func(pin string) bool {
+		if !pattern.IsPattern(pin) {
+			return false
+		}
+
+		list, err := pattern.Compile([]string{pin})
+		return err == nil && list.Match(name)
+	})
+}
+
+// pinnedPrefix returns the prefix to show before name, which is empty when
+// name isn't pinned. Tags and commits are never pinned.
+func (r *Renderer) pinnedPrefix(name string) string {
+	if !r.listingBranches() {
+		return ""
+	}
+
+	if lo.Contains(*r.cfg.PinnedBranches, name) {
+		return r.cfg.PinnedBranchPrefix
+	}
+
+	if _, ok := r.pinningPattern(name); ok {
+		return r.patternPinPrefix()
+	}
+
+	return ""
+}
+
+// patternPinPrefix returns the prefix shown before branches pinned by a
+// pattern.
+func (r *Renderer) patternPinPrefix() string {
+	if r.cfg.PatternPinPrefix == "" {
+		return r.cfg.PinnedBranchPrefix
+	}
+
+	return r.cfg.PatternPinPrefix
+}
+
+// pinOf returns the entry in the pinned branches that pins name, which is
+// either name itself or the pattern matching it.
+func (r *Renderer) pinOf(name string) (string, bool) {
+	if lo.Contains(*r.cfg.PinnedBranches, name) {
+		return name, true
+	}
+
+	return r.pinningPattern(name)
+}
+
+// movePin moves the pin of the named branch up or down within the pinned
+// branches, past the pin of the branch listed before or after it. Moving a
+// branch pinned by a pattern moves the pattern along with every branch
+// matching it.
+func (r *Renderer) movePin(handler SelectionHandler, name string, delta int) {
+	pin, ok := r.pinOf(name)
+	if !ok {
+		r.state.Message = fmt.Sprintf("%v isn't pinned", name)
+		return
+	}
+
+	// Work out the order the pins are listed in, leaving out those that
+	// don't pin any branch.
+	names := lo.Map(r.cfg.Branches, func(b git.Branch, _ int) string {
+		return b.Name
+	})
+	listed := lo.Uniq(lo.FilterMap(r.pinnedBranches(names), func(name string, _ int) (string, bool) {
+		return r.pinOf(name)
+	}))
+
+	target := lo.IndexOf(listed, pin) + delta
+	if target < 0 || target >= len(listed) {
+		return
+	}
+
+	position := lo.IndexOf(*r.cfg.PinnedBranches, listed[target])
+	if handler.OnMovePin != nil {
+		if err := handler.OnMovePin(pin, position+1); err != nil {
+			r.state.Message = fmt.Sprintf("error: %v", err)
+			return
+		}
+	}
+
+	*r.cfg.PinnedBranches = movePin(*r.cfg.PinnedBranches, pin, position)
+	r.refreshBranchListWithSelection(name, true)
+}
+
+// movePin returns pins with pin moved to index position.
+func movePin(pins []string, pin string, position int) []string {
+	result := slices.DeleteFunc(slices.Clone(pins), func(p string) bool {
+		return p == pin
+	})
+
+	return slices.Insert(result, min(max(position, 0), len(result)), pin)
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// previewSideBySideWidth is the narrowest the terminal can be for the preview
+// pane to be shown to the right of the list instead of below it.
+const previewSideBySideWidth = 120
+
+// previewLines returns the lines to display in the preview pane for the
+// highlighted branch, starting the load in the background if needed.
+func (r *Renderer) previewLines(name string) []string {
+	branch, _ := r.branch(name)
+
+	// Previews are cached by ref, since deleting a local branch leaves its
+	// name to a remote branch, and a tag can share its name with a branch.
+	result, ok := r.previews.get(branch.Ref, func() ([]string, error) {
+		return r.cfg.Preview(branch)
+	})
+
+	// With HEAD detached, previews list the commits that aren't on the commit
+	// it is detached at.
+	checkedOut := r.cfg.CurrentBranch
+	if checkedOut == "" {
+		checkedOut = r.cfg.DetachedHead
+	}
+
+	switch {
+	case !ok:
+		return []string{"loading..."}
+	case result.err != nil:
+		return []string{fmt.Sprintf("error: %v", result.err)}
+	case len(result.value) == 0 && name != r.cfg.CurrentBranch:
+		return []string{fmt.Sprintf("no commits that aren't on %v", checkedOut)}
+	}
+
+	return result.value
+}
+
+// drawPreview draws the preview pane for the highlighted branch. listRow is
+// the row the branch list starts on.
+func (r *Renderer) drawPreview(listRow int) {
+	// Commits are described by their columns, so there's nothing to preview.
+	if r.cfg.Preview == nil || !r.state.ShowPreview || r.state.Mode == modeCommits {
+		return
+	}
+
+	name, ok := r.selectedBranch()
+	if !ok {
+		return
+	}
+	if name != r.state.PreviewBranch {
+		r.state.PreviewBranch = name
+		r.state.PreviewScroll = 0
+	}
+
+	width, height := r.screen.Size()
+	borderStyle := r.NormalStyle.Dim(true)
+
+	// Work out where the pane goes. Wide terminals get the pane to the right
+	// of the list, narrow ones get it below.
+	var left, top, paneWidth, paneHeight int
+	if width >= previewSideBySideWidth {
+		left = width - width*2/5
+		top = listRow
+		paneWidth = width - left - 2
+		paneHeight = height - top
+
+		for row := top; row < height; row++ {
+			r.screen.SetContent(left, row, '│', nil, borderStyle)
+			for col := left + 1; col < width; col++ {
+				r.screen.SetContent(col, row, ' ', nil, r.NormalStyle)
+			}
+		}
+		left += 2
+	} else {
+		top = listRow + r.WindowSize + 1
+		paneWidth = width
+		paneHeight = height - top - 1
+		if paneHeight < 1 {
+			return
+		}
+
+		for col := 0; col < width; col++ {
+			r.screen.SetContent(col, top, '─', nil, borderStyle)
+		}
+		top++
+	}
+
+	lines := r.previewLines(name)
+	r.state.PreviewScroll = max(0, min(r.state.PreviewScroll, len(lines)-paneHeight))
+
+	for i, line := range lines[r.state.PreviewScroll:] {
+		if i >= paneHeight {
+			break
+		}
+
+		col := 0
+		for _, ch := range line {
+			if col >= paneWidth {
+				break
+			}
+			r.screen.SetContent(left+col, top+i, ch, nil, r.NormalStyle)
+			col++
+		}
+	}
+}
+
+// scrollPreview scrolls the preview pane by delta lines.
+func (r *Renderer) scrollPreview(delta int) {
+	r.state.PreviewScroll = max(0, r.state.PreviewScroll+delta)
+}
+
+// notify wakes up Run so that the screen is redrawn after something finishes
+// loading in the background.
+func (r *Renderer) notify() {
+	_ = r.screen.PostEvent(tcell.NewEventInterrupt(nil))
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/samber/lo"
+)
+
+// promptOption is a single choice offered by a prompt.
+type promptOption struct {
+	// key is the rune the user presses to choose the option.
+	key rune
+	// label describes the option.
+	label string
+	// run is called when the option is chosen.
+	run func() error
+}
+
+// prompt is an inline question shown in place of the search input. While a
+// prompt is open all key presses are routed to it.
+type prompt struct {
+	message string
+	options []promptOption
+	// submit is set for prompts asking for text rather than offering
+	// options. It is called with the text typed when Enter is pressed.
+	submit func(text string) error
+	// input is the text typed so far.
+	input string
+}
+
+// openPrompt shows a prompt with the given message and options.
+func (r *Renderer) openPrompt(message string, options ...promptOption) {
+	r.state.Prompt = &prompt{
+		message: message,
+		options: options,
+	}
+}
+
+// openInputPrompt shows a prompt with the given message asking for text,
+// which is passed to submit when the user presses Enter.
+func (r *Renderer) openInputPrompt(message string, submit func(text string) error) {
+	r.state.Prompt = &prompt{
+		message: message,
+		submit:  submit,
+	}
+}
+
+// drawPrompt draws the open prompt on row.
+func (r *Renderer) drawPrompt(row int) {
+	col := 0
+	orangeStyle := r.NormalStyle.Foreground(tcell.ColorOrange)
+
+	for _, ch := range r.state.Prompt.message + " " {
+		r.screen.SetContent(col, row, ch, nil, r.InputStyle)
+		col++
+	}
+
+	choices := lo.Map(r.state.Prompt.options, func(o promptOption, _ int) []string {
+		return []string{string(o.key), o.label}
+	})
+
+	if r.state.Prompt.submit != nil {
+		for _, ch := range r.state.Prompt.input {
+			r.screen.SetContent(col, row, ch, nil, r.InputStyle)
+			col++
+		}
+		choices = append(choices, []string{"enter", "confirm"})
+	}
+
+	choices = append(choices, []string{"esc", "cancel"})
+
+	for _, choice := range choices {
+		for _, ch := range fmt.Sprintf(" [%v]", choice[0]) {
+			r.screen.SetContent(col, row, ch, nil, orangeStyle)
+			col++
+		}
+		for _, ch := range " " + choice[1] {
+			r.screen.SetContent(col, row, ch, nil, r.NormalStyle)
+			col++
+		}
+	}
+}
+
+// handlePrompt routes a key press to the open prompt, closing it when an
+// option is chosen or the prompt is cancelled.
+func (r *Renderer) handlePrompt(ev *tcell.EventKey) error {
+	switch ev.Key() {
+	case tcell.KeyEsc, tcell.KeyCtrlC:
+		r.state.Prompt = nil
+		return nil
+	}
+
+	if r.state.Prompt.submit != nil {
+		return r.handleInputPrompt(ev)
+	}
+
+	switch ev.Key() {
+	case tcell.KeyRune:
+		option, found := lo.Find(r.state.Prompt.options, func(o promptOption) bool {
+			return unicode.ToLower(o.key) == unicode.ToLower(ev.Rune())
+		})
+		if !found {
+			return nil
+		}
+
+		// Close the prompt before running the option so the option can open
+		// a follow up prompt.
+		r.state.Prompt = nil
+		return option.run()
+	}
+
+	return nil
+}
+
+// handleInputPrompt routes a key press to the open prompt asking for text.
+func (r *Renderer) handleInputPrompt(ev *tcell.EventKey) error {
+	p := r.state.Prompt
+
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		// Close the prompt before submitting so a follow up prompt can be
+		// opened.
+		r.state.Prompt = nil
+		return p.submit(p.input)
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if runes := []rune(p.input); len(runes) > 0 {
+			p.input = string(runes[:len(runes)-1])
+		}
+	case tcell.KeyRune:
+		p.input += string(ev.Rune())
+	}
+
+	return nil
+}
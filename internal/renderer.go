@@ -2,9 +2,13 @@ package internal
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
 	"github.com/samber/lo"
 )
 
@@ -14,6 +18,18 @@ type state struct {
 	Selected    int
 	Quit        bool
 	WindowStart int
+	Sort        string
+	Mode        string
+	Prompt      *prompt
+	Message     string
+	Hidden      []string
+	ShowHidden  bool
+	// Log holds the commits listed in commits mode.
+	Log *commitLog
+
+	ShowPreview   bool
+	PreviewBranch string
+	PreviewScroll int
 }
 
 type Renderer struct {
@@ -24,46 +40,126 @@ type Renderer struct {
 	SelectedBold       tcell.Style
 	InputStyle         tcell.Style
 	CurrentBranchStyle tcell.Style
+	ColumnStyle        tcell.Style
+	MessageStyle       tcell.Style
 	WindowSize         int
-	filter             func(input string) []string
 
 	cfg         RendererConfig
 	state       *state
 	searchLabel string
+	previews    *asyncCache[string, []string]
+	aheadBehind *asyncCache[aheadBehindKey, git.AheadBehind]
+	commits     *asyncCache[string, []git.Branch]
 }
 
 type RendererConfig struct {
-	Branches           []string
+	Branches           []git.Branch
 	PinnedBranches     *[]string
 	WindowSize         int
 	SearchLabel        string
 	PinnedBranchPrefix string
-	CurrentBranch      string
+	// PatternPinPrefix is shown before branches that are pinned because
+	// they match a pattern in PinnedBranches. PinnedBranchPrefix is used
+	// when it is empty.
+	PatternPinPrefix string
+	// QuickSelect is how the first nine pinned branches, which are numbered,
+	// are selected by their number. Either QuickSelectAlt, the default, or
+	// QuickSelectDigit.
+	QuickSelect   string
+	CurrentBranch string
+	// DetachedHead describes the commit HEAD is detached at. It is shown in
+	// place of the current branch when CurrentBranch is empty.
+	DetachedHead string
+	Columns      []string
+	Sort         string
+	// RecentlyCheckedOut lists branch names, most recently checked out
+	// first. It is used when sorting by SortCheckout.
+	RecentlyCheckedOut []string
+	// Preview returns the lines to show in the preview pane for a branch.
+	// It is called in the background. The preview pane is disabled when
+	// Preview is nil.
+	Preview func(branch git.Branch) ([]string, error)
+	// ShowPreview controls whether the preview pane is initially shown.
+	ShowPreview bool
+	// AheadBehind counts the commits ref is ahead of and behind base. It is
+	// called in the background, and only for visible branches, to populate
+	// the ahead/behind columns.
+	AheadBehind func(ref, base string) (git.AheadBehind, error)
+	// AllowCreate offers to create a branch named after the search input
+	// when no branch has that name.
+	AllowCreate bool
+	// Remotes lists the remotes a newly created branch can be pushed to.
+	Remotes []string
+	// History lists previously visited branches, most recent first, with
+	// the commits HEAD was detached at by their full hash. When set, the
+	// user can switch to a history mode listing only these branches.
+	History []string
+	// HiddenBranches lists branches that aren't listed unless the user
+	// chooses to show hidden branches. Pinned branches are always listed.
+	HiddenBranches []string
+	// Commits returns the commits on a branch or tag, most recent first, as
+	// branches named after their abbreviated hash. When set, the user can
+	// drill down into the highlighted branch to list its commits, and select
+	// one to check it out detached or create a branch at it.
+	Commits func(branch git.Branch) ([]git.Branch, error)
+	// Tags lists the tags of the repository. When set, the user can switch
+	// to a tags mode listing them instead of the branches, and select one to
+	// check it out detached.
+	Tags []git.Branch
+	// Status is the status of the working tree, shown next to the current
+	// branch. Selecting a branch while an operation is in progress asks
+	// for confirmation first. No status is shown when Status is nil.
+	Status *git.Status
+	// LocalChanges is set when the working tree has uncommitted changes.
+	// Selecting a branch then asks whether to stash the changes or carry
+	// them to the branch.
+	LocalChanges bool
+	// Worktrees maps branches checked out in other worktrees to the path of
+	// the worktree. Selecting one of them offers to go to its worktree.
+	Worktrees map[string]string
+	// WorktreePath returns the path to create a worktree for a branch at.
+	// Creating worktrees is disabled when WorktreePath is nil.
+	WorktreePath func(branch string) string
+	// Screen is the screen to draw on, such as a tcell.SimulationScreen in
+	// tests. A screen for the terminal is used when Screen is nil. Either
+	// way, the screen is initialized by NewRenderer and finalized by Finish.
+	Screen tcell.Screen
 }
 
-func NewRenderer(cfg RendererConfig) (*Renderer, error) {
-	// Only include pinned branches if they are real branches.
-	pinnedBranches := lo.Filter(*cfg.PinnedBranches, func(s string, _ int) bool {
-		return lo.Contains(cfg.Branches, s)
-	})
-
-	// Remove pinned branches from normal branches
-	normalBranches := lo.Filter(cfg.Branches, func(s string, _ int) bool {
-		return !lo.Contains(pinnedBranches, s)
-	})
-
-	allBranches := append(pinnedBranches, normalBranches...)
+// Modes the branch list can be in.
+const (
+	// modeBranches lists every branch.
+	modeBranches = "branches"
+	// modeHistory lists previously visited branches, most recent first.
+	modeHistory = "history"
+	// modeTags lists tags instead of branches.
+	modeTags = "tags"
+	// modeCommits lists the commits on a branch or tag.
+	modeCommits = "commits"
+)
 
+func NewRenderer(cfg RendererConfig) (*Renderer, error) {
 	state := &state{
 		Input:       "",
-		Branches:    allBranches,
 		Selected:    0,
 		WindowStart: 0,
+		Sort:        SortAlphabetical,
+		Mode:        modeBranches,
+		ShowPreview: cfg.ShowPreview,
+		Hidden:      append([]string{}, cfg.HiddenBranches...),
 	}
 
-	screen, err := tcell.NewScreen()
-	if err != nil {
-		return nil, err
+	if lo.Contains(SortModes, cfg.Sort) {
+		state.Sort = cfg.Sort
+	}
+
+	screen := cfg.Screen
+	if screen == nil {
+		var err error
+		screen, err = tcell.NewScreen()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if err := screen.Init(); err != nil {
@@ -82,6 +178,8 @@ func NewRenderer(cfg RendererConfig) (*Renderer, error) {
 		SelectedBold:       tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite).Bold(true),
 		InputStyle:         tcell.StyleDefault.Foreground(tcell.ColorGreen),
 		CurrentBranchStyle: tcell.StyleDefault.Foreground(tcell.ColorBlueViolet),
+		ColumnStyle:        tcell.StyleDefault.Dim(true),
+		MessageStyle:       tcell.StyleDefault.Foreground(tcell.ColorRed),
 		WindowSize:         cfg.WindowSize,
 
 		screen:      screen,
@@ -90,45 +188,33 @@ func NewRenderer(cfg RendererConfig) (*Renderer, error) {
 		cfg:         cfg,
 	}
 
-	// Create the filter function that references the renderer's config
-	filter := func(input string) []string {
-		// Recalculate pinned and normal branches fresh each time using renderer's current config
-		currentPinnedBranches := lo.Filter(*renderer.cfg.PinnedBranches, func(s string, _ int) bool {
-			return lo.Contains(renderer.cfg.Branches, s)
-		})
-
-		// Remove pinned branches from normal branches
-		currentNormalBranches := lo.Filter(renderer.cfg.Branches, func(s string, _ int) bool {
-			return !lo.Contains(currentPinnedBranches, s)
-		})
-
-		// Create a fresh slice each time to avoid sharing issues
-		allBranches := make([]string, 0, len(currentPinnedBranches)+len(currentNormalBranches))
-		allBranches = append(allBranches, currentPinnedBranches...)
-		allBranches = append(allBranches, currentNormalBranches...)
-
-		if input == "" {
-			// Return a copy to avoid slice sharing
-			result := make([]string, len(allBranches))
-			copy(result, allBranches)
-			return result
-		}
-
-		// Filter all branches
-		filtered := lo.Filter(allBranches, func(s string, _ int) bool {
-			return strings.Contains(strings.ToLower(s), strings.ToLower(input))
-		})
-
-		// Return deduplicated result
-		return lo.Uniq(filtered)
-	}
+	renderer.previews = newAsyncCache[string, []string](renderer.notify)
+	renderer.aheadBehind = newAsyncCache[aheadBehindKey, git.AheadBehind](renderer.notify)
+	renderer.commits = newAsyncCache[string, []git.Branch](renderer.notify)
+	state.Branches = renderer.filter(state.Input)
 
-	renderer.filter = filter
 	return renderer, nil
 }
 
+// hotkeyHint is a hotkey listed at the top of the screen.
+type hotkeyHint struct {
+	key         string
+	description string
+}
+
 func (r *Renderer) Draw() {
 	r.screen.Clear()
+	r.previews.nextFrame()
+	r.aheadBehind.nextFrame()
+	r.commits.nextFrame()
+	r.loadCommits()
+
+	// The counts branches are sorted by load in the background, so the list
+	// is sorted again as they arrive, keeping the selected branch selected.
+	if r.state.Sort == SortAheadBehind && r.state.Mode == modeBranches {
+		selected, _ := r.selectedBranch()
+		r.refreshBranchListWithSelection(selected, true)
+	}
 
 	row := 0
 
@@ -137,43 +223,74 @@ func (r *Renderer) Draw() {
 	orangeStyle := r.NormalStyle.Foreground(tcell.ColorOrange)
 	dimStyle := r.NormalStyle.Dim(true)
 
-	// CTRL+D in orange
-	ctrlD := "CTRL+D"
-	for _, ch := range ctrlD {
-		r.screen.SetContent(col, row, ch, nil, orangeStyle)
-		col++
-	}
+	hotkeys := []hotkeyHint{}
 
-	// Rest of first instruction in dimmed text
-	firstRest := ": Pin Selected Branch, "
-	for _, ch := range firstRest {
-		r.screen.SetContent(col, row, ch, nil, dimStyle)
-		col++
+	// Tags and commits can only be checked out, so the hotkeys acting on
+	// branches are left out while they are listed.
+	if r.listingBranches() {
+		hotkeys = append(hotkeys, r.branchHotkeys()...)
 	}
 
-	// CTRL+U in orange
-	ctrlU := "CTRL+U"
-	for _, ch := range ctrlU {
-		r.screen.SetContent(col, row, ch, nil, orangeStyle)
-		col++
+	if r.state.Mode == modeCommits {
+		hotkeys = append(hotkeys, hotkeyHint{"ENTER", "Check Out or Branch From Commit"}, hotkeyHint{"ESC", "Back"})
+	} else {
+		hotkeys = append(hotkeys, r.listHotkeys()...)
 	}
 
-	// Rest of second instruction in dimmed text
-	secondRest := ": Unpin Selected Branch"
-	for _, ch := range secondRest {
-		r.screen.SetContent(col, row, ch, nil, dimStyle)
-		col++
+	width, _ := r.screen.Size()
+	for i, hotkey := range hotkeys {
+		// Wrap onto the next line rather than running off the screen
+		if col > 0 && col+len(hotkey.key)+len(hotkey.description)+4 > width {
+			row++
+			col = 0
+		}
+
+		// Hotkey in orange
+		for _, ch := range hotkey.key {
+			r.screen.SetContent(col, row, ch, nil, orangeStyle)
+			col++
+		}
+
+		// Rest of the instruction in dimmed text
+		rest := fmt.Sprintf(": %v", hotkey.description)
+		if i < len(hotkeys)-1 {
+			rest += ", "
+		}
+		for _, ch := range rest {
+			r.screen.SetContent(col, row, ch, nil, dimStyle)
+			col++
+		}
 	}
 	row++
 
 	// 2. Empty line after hotkey instructions
 	row++
 
-	// 3. Draw current branch
-	if r.cfg.CurrentBranch != "" {
-		currentBranchLabel := fmt.Sprintf("checked out: %v", r.cfg.CurrentBranch)
-		for i, ch := range currentBranchLabel {
-			r.screen.SetContent(i, row, ch, nil, r.CurrentBranchStyle)
+	// 3. Draw current branch, or the commit HEAD is detached at, followed by
+	// the status of the working tree
+	checkedOut := r.cfg.CurrentBranch
+	if checkedOut == "" && r.cfg.DetachedHead != "" {
+		checkedOut = fmt.Sprintf("HEAD detached at %v", r.cfg.DetachedHead)
+	}
+	if checkedOut != "" || r.cfg.Status != nil {
+		col := 0
+		if checkedOut != "" {
+			for _, ch := range fmt.Sprintf("checked out: %v  ", checkedOut) {
+				r.screen.SetContent(col, row, ch, nil, r.CurrentBranchStyle)
+				col++
+			}
+		}
+		if r.cfg.Status != nil {
+			for _, ch := range statusSummary(*r.cfg.Status) {
+				r.screen.SetContent(col, row, ch, nil, r.ColumnStyle)
+				col++
+			}
+			if r.cfg.Status.Operation != "" {
+				for _, ch := range fmt.Sprintf("  %v in progress", r.cfg.Status.Operation) {
+					r.screen.SetContent(col, row, ch, nil, r.MessageStyle)
+					col++
+				}
+			}
 		}
 		row++
 	}
@@ -181,24 +298,82 @@ func (r *Renderer) Draw() {
 	// 4. Empty line after current branch
 	row++
 
-	// 5. Draw input at the next line
-	inputPrompt := fmt.Sprintf("%v: %v", r.searchLabel, r.state.Input)
-	for i, ch := range inputPrompt {
-		r.screen.SetContent(i, row, ch, nil, r.InputStyle)
+	// 5. Draw input at the next line, or the open prompt if there is one
+	if r.state.Prompt != nil {
+		r.drawPrompt(row)
+	} else {
+		searchLabel := r.searchLabel
+		switch r.state.Mode {
+		case modeHistory:
+			searchLabel = "search history"
+		case modeTags:
+			searchLabel = "search tags"
+		case modeCommits:
+			searchLabel = fmt.Sprintf("search commits on %v", r.state.Log.branch.Name)
+		}
+		inputPrompt := fmt.Sprintf("%v: %v", searchLabel, r.state.Input)
+		for i, ch := range inputPrompt {
+			r.screen.SetContent(i, row, ch, nil, r.InputStyle)
+		}
 	}
 	row++
 
-	// 6. Empty line after input
+	// 6. Empty line after input, used for status messages
+	if r.state.Message != "" {
+		// Only the first line fits, which is where git explains errors
+		message, _, _ := strings.Cut(r.state.Message, "\n")
+		for i, ch := range message {
+			r.screen.SetContent(i, row, ch, nil, r.MessageStyle)
+		}
+	}
 	row++
 
-	// 7. Draw list starting at the next line
+	// 7. Work out how wide the name and each column should be so that
+	// columns line up for every branch in the list.
+	now := time.Now()
+	hasRemotes := lo.SomeBy(r.items(), func(b git.Branch) bool {
+		return len(b.Remotes) > 0
+	})
+	hasWorktrees := len(r.cfg.Worktrees) > 0 && r.listingBranches()
+	nameWidth := columnWidth(columnName, r.items(), now)
+	if hasRemotes {
+		nameWidth += 2
+	}
+	if hasWorktrees {
+		nameWidth += 2
+	}
+	numbered := r.numberedBranches()
+	if len(numbered) > 0 {
+		nameWidth += max(len([]rune(r.cfg.PinnedBranchPrefix)), len([]rune(r.patternPinPrefix()))) + 3
+	}
+	columns := r.columns()
+	columnWidths := lo.Map(columns, func(column string, idx int) int {
+		// The last column is never padded.
+		if idx == len(columns)-1 {
+			return 0
+		}
+		return columnWidth(column, r.items(), now)
+	})
+
+	// 8. Draw list starting at the next line
+	listRow := row
 	end := min(r.state.WindowStart+r.WindowSize, len(r.state.Branches))
 	for i := r.state.WindowStart; i < end; i++ {
 		item := r.state.Branches[i]
-		isPinned := lo.Contains(*r.cfg.PinnedBranches, item)
-		lowerItem := strings.ToLower(item)
-		lowerInput := strings.ToLower(r.state.Input)
-		start := strings.Index(lowerItem, lowerInput)
+
+		// Render the create branch row in the input style
+		if item == createBranchRow {
+			style := r.InputStyle
+			if i == r.state.Selected {
+				style = r.SelectedStyle
+			}
+			for j, ch := range r.createRowLabel() {
+				r.screen.SetContent(j, row+i-r.state.WindowStart, ch, nil, style)
+			}
+			continue
+		}
+
+		pinnedPrefix := r.pinnedPrefix(item)
 		col := 0
 		isSelected := i == r.state.Selected
 		style := r.NormalStyle
@@ -206,53 +381,271 @@ func (r *Renderer) Draw() {
 		if isSelected {
 			style = r.SelectedStyle
 			bold = r.SelectedBold
+		} else if r.isHidden(item) {
+			// Hidden branches are dimmed when they are shown
+			style = r.ColumnStyle
+			bold = r.ColumnStyle.Bold(true)
 		}
-		// Render the pinned prefix in normal style, never selected/bold
-		if isPinned {
-			pinnedPrefix := fmt.Sprintf("%v ", r.cfg.PinnedBranchPrefix)
-			for _, ch := range pinnedPrefix {
+		branch, _ := r.branch(item)
+		// Render where the branch lives in normal style, never selected/bold
+		if hasRemotes {
+			marker := fmt.Sprintf("%v ", locationMarker(branch))
+			for _, ch := range marker {
 				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, r.NormalStyle)
 				col++
 			}
 		}
-		// Render the branch name (with selection/match logic)
-		if r.state.Input != "" && start != -1 {
-			// Before match
-			for _, ch := range item[:start] {
-				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, style)
-				col++
+		// Render whether the branch is checked out in another worktree
+		if hasWorktrees {
+			marker := "  "
+			if _, ok := r.cfg.Worktrees[item]; ok {
+				marker = markerWorktree + " "
 			}
-			// Match in bold
-			for _, ch := range item[start : start+len(r.state.Input)] {
-				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, bold)
+			for _, ch := range marker {
+				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, r.NormalStyle)
 				col++
 			}
-			// After match
-			for _, ch := range item[start+len(r.state.Input):] {
-				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, style)
+		}
+		// Render the quick select number of pinned branches
+		if n := lo.IndexOf(numbered, item); n != -1 {
+			for _, ch := range fmt.Sprintf("%v ", n+1) {
+				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, r.ColumnStyle)
 				col++
 			}
-		} else {
-			for _, ch := range item {
-				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, style)
+		} else if pinnedPrefix != "" {
+			// Line up pinned branches that aren't numbered with those that
+			// are.
+			col += 2
+		}
+		// Render the pinned prefix in normal style, never selected/bold
+		if pinnedPrefix != "" {
+			for _, ch := range pinnedPrefix + " " {
+				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, r.NormalStyle)
 				col++
 			}
 		}
+		// Render the branch name, highlighting each matched rune in bold
+		var matched []int
+		if r.state.Input != "" {
+			_, matched, _ = fuzzyMatch(r.state.Input, item)
+		}
+		for j, ch := range []rune(item) {
+			chStyle := style
+			if lo.Contains(matched, j) {
+				chStyle = bold
+			}
+			r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, chStyle)
+			col += runewidth.RuneWidth(ch)
+		}
+
+		if len(columns) == 0 {
+			continue
+		}
+
+		// Render the configured columns after the name, dimmed unless the
+		// branch is selected.
+		columnStyle := r.ColumnStyle
+		if isSelected {
+			columnStyle = style
+		}
+		for ; col < nameWidth; col++ {
+			r.screen.SetContent(col, row+i-r.state.WindowStart, ' ', nil, style)
+		}
+		for idx, column := range columns {
+			value := "  " + fitText(r.columnValue(column, branch, now), columnWidths[idx])
+			for _, ch := range value {
+				r.screen.SetContent(col, row+i-r.state.WindowStart, ch, nil, columnStyle)
+				col += runewidth.RuneWidth(ch)
+			}
+		}
+	}
+
+	if r.state.Log != nil && r.state.Log.commits == nil {
+		for i, ch := range "loading commits..." {
+			r.screen.SetContent(i, listRow, ch, nil, r.ColumnStyle)
+		}
 	}
 
+	// 9. Draw the preview pane for the selected branch over the list
+	r.drawPreview(listRow)
+
 	r.screen.Show()
 }
 
+// listHotkeys returns the hotkeys changing what is listed and how, along
+// with creating a branch from the search input.
+func (r *Renderer) listHotkeys() []hotkeyHint {
+	hotkeys := []hotkeyHint{
+		{"CTRL+O", fmt.Sprintf("Sort (%v)", r.state.Sort)},
+	}
+
+	if r.cfg.History != nil {
+		if r.state.Mode == modeHistory {
+			hotkeys = append(hotkeys, hotkeyHint{"CTRL+R", "All Branches"})
+		} else {
+			hotkeys = append(hotkeys, hotkeyHint{"CTRL+R", "History"})
+		}
+	}
+
+	if r.cfg.Tags != nil {
+		if r.state.Mode == modeTags {
+			hotkeys = append(hotkeys, hotkeyHint{"CTRL+G", "All Branches"})
+		} else {
+			hotkeys = append(hotkeys, hotkeyHint{"CTRL+G", "Tags"})
+		}
+	}
+
+	if r.cfg.Commits != nil {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+L", "Browse Commits"})
+	}
+
+	if r.cfg.Preview != nil {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+P", "Toggle Preview"})
+	}
+
+	if r.canCreateBranch(r.state.Input) {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+N", "Create Branch From Selected"})
+	}
+
+	return hotkeys
+}
+
+// branchHotkeys returns the hotkeys acting on the selected branch.
+func (r *Renderer) branchHotkeys() []hotkeyHint {
+	hotkeys := []hotkeyHint{
+		{"CTRL+D", "Pin Selected Branch"},
+		{"CTRL+U", "Unpin Selected Branch"},
+		{"CTRL+X", "Delete Selected Branch"},
+	}
+
+	if len(r.numberedBranches()) > 0 {
+		if r.cfg.QuickSelect == QuickSelectDigit && r.state.Input == "" {
+			hotkeys = append(hotkeys, hotkeyHint{"1-9", "Switch to Pinned Branch"})
+		} else {
+			hotkeys = append(hotkeys, hotkeyHint{"ALT+1-9", "Switch to Pinned Branch"})
+		}
+	}
+
+	if selected, ok := r.selectedBranch(); ok && r.pinnedPrefix(selected) != "" {
+		hotkeys = append(hotkeys, hotkeyHint{"SHIFT+UP/DOWN", "Move Pinned Branch"})
+	}
+
+	if r.cfg.WorktreePath != nil {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+W", "Create Worktree"})
+	}
+
+	if selected, ok := r.selectedBranch(); ok && r.isHidden(selected) {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+K", "Unhide Selected Branch"})
+	} else {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+K", "Hide Selected Branch"})
+	}
+
+	if r.state.ShowHidden {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+T", "Hide Hidden Branches"})
+	} else if len(r.state.Hidden) > 0 {
+		hotkeys = append(hotkeys, hotkeyHint{"CTRL+T", fmt.Sprintf("Show Hidden Branches (%v)", len(r.state.Hidden))})
+	}
+
+	return hotkeys
+}
+
+// Markers displayed in front of each branch to show where it exists.
+const (
+	markerLocal      = " "
+	markerRemote     = "↕"
+	markerRemoteOnly = "↓"
+	// markerWorktree marks branches checked out in another worktree.
+	markerWorktree = "⌂"
+)
+
+// locationMarker returns the marker for where b exists.
+func locationMarker(b git.Branch) string {
+	switch {
+	case b.IsRemoteOnly():
+		return markerRemoteOnly
+	case len(b.Remotes) > 0:
+		return markerRemote
+	}
+
+	return markerLocal
+}
+
+// Selection is the result of selecting a branch.
+type Selection struct {
+	// Branch is the selected branch.
+	Branch git.Branch
+	// Remote is the remote to create a tracking branch from when Branch only
+	// exists on remotes.
+	Remote string
+	// Create is set when Branch doesn't exist yet and should be created.
+	Create bool
+	// Base is the ref a created branch should start from, or empty to start
+	// from HEAD.
+	Base string
+	// Detach is set when Branch is a tag or commit to check out with HEAD
+	// detached at it, rather than a branch. Its Ref is what to check out.
+	Detach bool
+	// Stash is set when the user chose to stash uncommitted changes before
+	// checking out the branch.
+	Stash bool
+	// Worktree is the path of a worktree the branch is checked out in, to go
+	// to instead of checking the branch out. When CreateWorktree is set, it
+	// is the path to create a worktree for the branch at.
+	Worktree       string
+	CreateWorktree bool
+	// PushRemote is the remote a created branch should be pushed to and set
+	// to track, or empty to not push it.
+	PushRemote string
+}
+
 type SelectionHandler struct {
-	OnSelect func(string)
+	OnSelect func(Selection)
 	OnPin    func(string) error
 	OnUnpin  func(string) error
+	// OnDelete deletes a local branch, forcing the deletion if force is
+	// set. It should return git.ErrBranchNotMerged if the branch can't be
+	// deleted because it isn't fully merged.
+	OnDelete func(branch string, force bool) error
+	// OnDeleteRemote deletes a branch from a remote.
+	OnDeleteRemote func(remote, branch string) error
+	// OnHide hides a branch from the list and OnUnhide lists it again.
+	OnHide   func(branch string) error
+	OnUnhide func(branch string) error
+	// OnMovePin moves a pinned branch or pattern to position in the pinned
+	// branches, 1 being the first.
+	OnMovePin func(pin string, position int) error
 }
 
 func (r *Renderer) Run(handler SelectionHandler) error {
 	ev := r.screen.PollEvent()
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
+		r.state.Message = ""
+
+		if r.state.Prompt != nil {
+			err := r.handlePrompt(ev)
+			r.Draw()
+			return err
+		}
+
+		if n, ok := r.quickSelectNumber(ev); ok {
+			r.quickSelect(handler, n)
+			r.Draw()
+			return nil
+		}
+
+		if r.state.Mode == modeCommits && r.handleCommitsKey(handler, ev) {
+			r.Draw()
+			return nil
+		}
+
+		// Commits in the history can only be checked out too.
+		selected, _ := r.selectedBranch()
+		if (!r.listingBranches() || r.isCommit(selected)) && lo.Contains(branchKeys, ev.Key()) {
+			r.Draw()
+			return nil
+		}
+
 		switch ev.Key() {
 		case tcell.KeyEsc, tcell.KeyCtrlC:
 			r.state.Quit = true
@@ -263,59 +656,139 @@ func (r *Renderer) Run(handler SelectionHandler) error {
 				r.state.WindowStart = 0
 			}
 		case tcell.KeyUp:
-			if r.state.Selected > 0 {
+			if ev.Modifiers()&tcell.ModShift != 0 {
+				// Move the selected pinned branch up
+				if selectedBranch, ok := r.selectedBranch(); ok {
+					r.movePin(handler, selectedBranch, -1)
+				}
+			} else if r.state.Selected > 0 {
 				r.state.Selected--
 				if r.state.Selected < r.state.WindowStart {
 					r.state.WindowStart--
 				}
 			}
 		case tcell.KeyDown:
-			if r.state.Selected < len(r.state.Branches)-1 {
+			if ev.Modifiers()&tcell.ModShift != 0 {
+				// Move the selected pinned branch down
+				if selectedBranch, ok := r.selectedBranch(); ok {
+					r.movePin(handler, selectedBranch, 1)
+				}
+			} else if r.state.Selected < len(r.state.Branches)-1 {
 				r.state.Selected++
 				if r.state.Selected >= r.state.WindowStart+r.WindowSize {
 					r.state.WindowStart++
 				}
 			}
 		case tcell.KeyEnter:
-			if len(r.state.Branches) > 0 {
-				r.state.Quit = true
-				if handler.OnSelect != nil {
-					handler.OnSelect(r.state.Branches[r.state.Selected])
-					return nil
-				}
+			if selected, ok := r.selectedBranch(); ok && (r.state.Mode == modeTags || r.isCommit(selected)) {
+				r.selectDetached(handler, selected)
+				r.Draw()
+				return nil
+			}
+			if r.isCreateRowSelected() {
+				r.createBranch(handler, "")
+				r.Draw()
+				return nil
+			}
+			if selectedBranch, ok := r.selectedBranch(); ok {
+				r.selectBranch(handler, selectedBranch)
+				r.Draw()
+				return nil
+			}
+		case tcell.KeyCtrlN:
+			// Create a branch named after the search input, starting from
+			// the selected branch
+			if selectedBranch, ok := r.selectedBranch(); ok && r.canCreateBranch(r.state.Input) {
+				r.createBranch(handler, selectedBranch)
+				r.Draw()
+				return nil
 			}
 		case tcell.KeyCtrlD:
 			// Pin the selected branch
-			if len(r.state.Branches) > 0 && handler.OnPin != nil {
-				selectedBranch := r.state.Branches[r.state.Selected]
+			if selectedBranch, ok := r.selectedBranch(); ok && handler.OnPin != nil && !lo.Contains(*r.cfg.PinnedBranches, selectedBranch) {
 				if err := handler.OnPin(selectedBranch); err != nil {
 					return err
 				}
-				// Update the pinned branches list in the renderer
+				// Update the pinned branches list in the renderer, unless the
+				// handler already did
 				if !lo.Contains(*r.cfg.PinnedBranches, selectedBranch) {
 					*r.cfg.PinnedBranches = append(*r.cfg.PinnedBranches, selectedBranch)
-					// Refresh the branch list and follow the pinned branch
-					r.refreshBranchListWithSelection(selectedBranch, true)
-					r.Draw()
-					return nil
 				}
+				// Refresh the branch list and follow the pinned branch
+				r.refreshBranchListWithSelection(selectedBranch, true)
+				r.Draw()
+				return nil
+			}
+		case tcell.KeyCtrlO:
+			// Cycle through the sort modes, keeping the selected branch
+			// selected.
+			var selectedBranch string
+			if len(r.state.Branches) > 0 {
+				selectedBranch = r.state.Branches[r.state.Selected]
+			}
+			r.state.Sort = nextSortMode(r.state.Sort)
+			r.refreshBranchListWithSelection(selectedBranch, true)
+			r.Draw()
+			return nil
+		case tcell.KeyCtrlX:
+			// Delete the selected branch
+			if selectedBranch, ok := r.selectedBranch(); ok && (handler.OnDelete != nil || handler.OnDeleteRemote != nil) {
+				r.deleteBranch(handler, selectedBranch)
+				r.Draw()
+				return nil
+			}
+		case tcell.KeyCtrlW:
+			// Create a worktree for the selected branch
+			if selectedBranch, ok := r.selectedBranch(); ok && r.cfg.WorktreePath != nil {
+				r.createWorktree(handler, selectedBranch)
+				r.Draw()
+				return nil
 			}
+		case tcell.KeyCtrlK:
+			// Hide the selected branch, or unhide it if it's hidden
+			if selectedBranch, ok := r.selectedBranch(); ok {
+				r.hideBranch(handler, selectedBranch)
+				r.Draw()
+				return nil
+			}
+		case tcell.KeyCtrlT:
+			r.toggleHidden()
+			r.Draw()
+			return nil
+		case tcell.KeyCtrlR:
+			// Toggle between listing every branch and the branch history
+			if r.cfg.History != nil {
+				if r.state.Mode == modeHistory {
+					r.state.Mode = modeBranches
+				} else {
+					r.state.Mode = modeHistory
+				}
+				r.state.Selected = 0
+				r.state.WindowStart = 0
+			}
+		case tcell.KeyCtrlG:
+			// Toggle between listing branches and tags
+			r.toggleTags()
+		case tcell.KeyCtrlL:
+			// List the commits on the selected branch or tag
+			if selected, ok := r.selectedBranch(); ok && r.cfg.Commits != nil {
+				r.browseCommits(selected)
+			}
+		case tcell.KeyCtrlP:
+			r.state.ShowPreview = !r.state.ShowPreview
+		case tcell.KeyPgUp:
+			r.scrollPreview(-max(1, r.WindowSize/2))
+		case tcell.KeyPgDn:
+			r.scrollPreview(max(1, r.WindowSize/2))
 		case tcell.KeyCtrlU:
 			// Unpin the selected branch
-			if len(r.state.Branches) > 0 && handler.OnUnpin != nil {
-				selectedBranch := r.state.Branches[r.state.Selected]
-				if err := handler.OnUnpin(selectedBranch); err != nil {
-					return err
+			if selectedBranch, ok := r.selectedBranch(); ok && handler.OnUnpin != nil {
+				if pin, ok := r.pinningPattern(selectedBranch); ok {
+					r.state.Message = fmt.Sprintf("%v is pinned by the pattern %v", selectedBranch, pin)
+					r.Draw()
+					return nil
 				}
-				// Update the pinned branches list in the renderer
-				if idx := lo.IndexOf(*r.cfg.PinnedBranches, selectedBranch); idx != -1 {
-					// Create a new slice to avoid memory corruption
-					pinnedBranches := *r.cfg.PinnedBranches
-					newPinnedBranches := make([]string, 0, len(pinnedBranches)-1)
-					newPinnedBranches = append(newPinnedBranches, pinnedBranches[:idx]...)
-					newPinnedBranches = append(newPinnedBranches, pinnedBranches[idx+1:]...)
-					*r.cfg.PinnedBranches = newPinnedBranches
-
+				if lo.Contains(*r.cfg.PinnedBranches, selectedBranch) {
 					// Find the next branch to select (stay in position instead of following)
 					var nextBranch string
 					if r.state.Selected+1 < len(r.state.Branches) {
@@ -323,6 +796,20 @@ func (r *Renderer) Run(handler SelectionHandler) error {
 					} else if r.state.Selected > 0 {
 						nextBranch = r.state.Branches[r.state.Selected-1]
 					}
+
+					if err := handler.OnUnpin(selectedBranch); err != nil {
+						return err
+					}
+					// Update the pinned branches list in the renderer, unless
+					// the handler already did
+					if idx := lo.IndexOf(*r.cfg.PinnedBranches, selectedBranch); idx != -1 {
+						// Create a new slice to avoid memory corruption
+						pinnedBranches := *r.cfg.PinnedBranches
+						newPinnedBranches := make([]string, 0, len(pinnedBranches)-1)
+						newPinnedBranches = append(newPinnedBranches, pinnedBranches[:idx]...)
+						newPinnedBranches = append(newPinnedBranches, pinnedBranches[idx+1:]...)
+						*r.cfg.PinnedBranches = newPinnedBranches
+					}
 					// Refresh the branch list and select the next branch
 					r.refreshBranchListWithSelection(nextBranch, false)
 					r.Draw()
@@ -350,12 +837,104 @@ func (r *Renderer) Run(handler SelectionHandler) error {
 			r.state.WindowStart = max(0, len(r.state.Branches)-r.WindowSize)
 		}
 
+		r.Draw()
+	case *tcell.EventInterrupt:
+		// Something finished loading in the background
+		r.Draw()
+	case *tcell.EventResize:
+		r.screen.Sync()
 		r.Draw()
 	}
 
 	return nil
 }
 
+// selectBranch selects the named branch. If the branch only exists on more
+// than one remote, the user is first asked which remote to track. If it is
+// checked out in another worktree, the user is offered to go there instead.
+func (r *Renderer) selectBranch(handler SelectionHandler, name string) {
+	r.confirmOperation(name, func() {
+		r.selectBranchAnyway(handler, name)
+	})
+}
+
+// selectBranchAnyway selects the named branch without warning about an
+// operation in progress.
+func (r *Renderer) selectBranchAnyway(handler SelectionHandler, name string) {
+	branch, _ := r.branch(name)
+
+	if path, ok := r.cfg.Worktrees[name]; ok {
+		r.openPrompt(fmt.Sprintf("%v is checked out in %v.", name, path),
+			promptOption{key: 'g', label: "go to worktree", run: func() error {
+				r.state.Quit = true
+				if handler.OnSelect != nil {
+					handler.OnSelect(Selection{Branch: branch, Worktree: path})
+				}
+				return nil
+			}},
+		)
+		return
+	}
+
+	r.chooseRemote(branch, func(remote string) error {
+		r.confirmLocalChanges(name, func(stash bool) error {
+			r.state.Quit = true
+			if handler.OnSelect != nil {
+				handler.OnSelect(Selection{Branch: branch, Remote: remote, Stash: stash})
+			}
+			return nil
+		})
+		return nil
+	})
+}
+
+// confirmLocalChanges calls finish with whether to stash the uncommitted
+// changes before checking out the named branch. If there are any, the user
+// is asked whether to stash them or carry them to the branch, and can cancel
+// switching instead.
+func (r *Renderer) confirmLocalChanges(name string, finish func(stash bool) error) {
+	if !r.cfg.LocalChanges || name == r.cfg.CurrentBranch {
+		finish(false)
+		return
+	}
+
+	r.openPrompt("You have uncommitted changes.",
+		promptOption{key: 's', label: "stash and switch", run: func() error {
+			return finish(true)
+		}},
+		promptOption{key: 'c', label: "carry changes", run: func() error {
+			return finish(false)
+		}},
+	)
+}
+
+// chooseRemote calls finish with the remote to track branch from. The remote
+// is empty for local branches. If the branch only exists on more than one
+// remote, the user is asked which remote to track.
+func (r *Renderer) chooseRemote(branch git.Branch, finish func(remote string) error) {
+	if !branch.IsRemoteOnly() {
+		finish("")
+		return
+	}
+
+	if len(branch.Remotes) == 1 {
+		finish(branch.Remotes[0])
+		return
+	}
+
+	options := lo.Map(branch.Remotes[:min(len(branch.Remotes), 9)], func(remote string, idx int) promptOption {
+		return promptOption{
+			key:   rune('1' + idx),
+			label: remote,
+			run: func() error {
+				return finish(remote)
+			},
+		}
+	})
+
+	r.openPrompt(fmt.Sprintf("Track %v from which remote?", branch.Name), options...)
+}
+
 func (r *Renderer) IsDone() bool {
 	return r.state.Quit
 }
@@ -364,43 +943,151 @@ func (r *Renderer) Finish() {
 	r.screen.Fini()
 }
 
-func (r *Renderer) refreshBranchListWithSelection(targetBranch string, followBranch bool) {
-	// Update the filter function to use the current branch configuration
-	filter := func(input string) []string {
-		// Recalculate pinned and normal branches fresh each time
-		currentPinnedBranches := lo.Filter(*r.cfg.PinnedBranches, func(s string, _ int) bool {
-			return lo.Contains(r.cfg.Branches, s)
+// filter returns the branches matching input, with pinned branches grouped
+// at the top and the remaining branches ordered by the current sort mode.
+// Within each group branches are ranked by how well they match input.
+func (r *Renderer) filter(input string) []string {
+	switch r.state.Mode {
+	case modeHistory:
+		return rankBranches(input, r.historyBranches())
+	case modeTags:
+		tags := lo.Map(sortBranches(r.state.Sort, r.cfg.Tags, r.cfg.RecentlyCheckedOut, nil), func(b git.Branch, _ int) string {
+			return b.Name
 		})
+		return rankBranches(input, tags)
+	case modeCommits:
+		return r.state.Log.rank(input)
+	}
 
-		// Remove pinned branches from normal branches
-		currentNormalBranches := lo.Filter(r.cfg.Branches, func(s string, _ int) bool {
-			return !lo.Contains(currentPinnedBranches, s)
-		})
+	var counts func(git.Branch) (git.AheadBehind, bool)
+	if r.state.Sort == SortAheadBehind {
+		counts = r.upstreamCounts
+	}
+	sortedBranches := sortBranches(r.state.Sort, r.cfg.Branches, r.cfg.RecentlyCheckedOut, counts)
+	branchNames := lo.Map(sortedBranches, func(b git.Branch, _ int) string {
+		return b.Name
+	})
+
+	pinnedBranches := r.pinnedBranches(branchNames)
+
+	// Remove pinned branches from normal branches, along with hidden
+	// branches unless they are being shown
+	normalBranches := lo.Filter(branchNames, func(s string, _ int) bool {
+		return !lo.Contains(pinnedBranches, s) && (r.state.ShowHidden || !r.isHidden(s))
+	})
 
-		// Create a fresh slice each time to avoid sharing issues
-		allBranches := make([]string, 0, len(currentPinnedBranches)+len(currentNormalBranches))
-		allBranches = append(allBranches, currentPinnedBranches...)
-		allBranches = append(allBranches, currentNormalBranches...)
+	// Create a fresh slice each time to avoid sharing issues
+	result := make([]string, 0, len(pinnedBranches)+len(normalBranches))
+	result = append(result, rankBranches(input, pinnedBranches)...)
+	result = append(result, rankBranches(input, normalBranches)...)
 
-		if input == "" {
-			// Return a copy to avoid slice sharing
-			result := make([]string, len(allBranches))
-			copy(result, allBranches)
-			return result
+	if r.canCreateBranch(input) {
+		result = append(result, createBranchRow)
+	}
+
+	return lo.Uniq(result)
+}
+
+// historyBranches returns the previously visited branches that still exist,
+// most recent first, leaving out the current branch. Commits HEAD was
+// detached at are listed as commit rows, see historyCommits.
+func (r *Renderer) historyBranches() []string {
+	names := lo.Map(lo.Uniq(r.cfg.History), func(entry string, _ int) string {
+		if git.IsFullHash(entry) {
+			return historyCommit(entry).Name
 		}
+		return entry
+	})
 
-		// Filter all branches
-		filtered := lo.Filter(allBranches, func(s string, _ int) bool {
-			return strings.Contains(strings.ToLower(s), strings.ToLower(input))
-		})
+	return lo.Filter(names, func(name string, _ int) bool {
+		_, exists := r.branch(name)
+		return exists && name != r.cfg.CurrentBranch
+	})
+}
+
+// historyCommits returns the commits in the history, which are recorded by
+// their full hash when HEAD was detached at them.
+func (r *Renderer) historyCommits() []git.Branch {
+	hashes := lo.Filter(lo.Uniq(r.cfg.History), func(entry string, _ int) bool {
+		return git.IsFullHash(entry)
+	})
+
+	return lo.Map(hashes, func(hash string, _ int) git.Branch {
+		return historyCommit(hash)
+	})
+}
+
+// historyCommit returns the commit row for a commit in the history, named
+// after its abbreviated hash like the commits listed by Commits.
+func historyCommit(hash string) git.Branch {
+	return git.Branch{Name: hash[:7], Ref: hash, Hash: hash}
+}
 
-		// Return deduplicated result
-		return lo.Uniq(filtered)
+// isCommit reports whether the named row is a commit rather than a branch,
+// which is only the case for commits in the history outside commits mode.
+func (r *Renderer) isCommit(name string) bool {
+	b, ok := r.branch(name)
+	return ok && r.state.Mode == modeHistory && git.IsFullHash(b.Ref)
+}
+
+// branch returns the branch with the given name, or the tag in tags mode.
+func (r *Renderer) branch(name string) (git.Branch, bool) {
+	return lo.Find(r.items(), func(b git.Branch) bool {
+		return b.Name == name
+	})
+}
+
+// rankBranches returns the branches that fuzzy match input, best match first.
+func rankBranches(input string, branches []string) []string {
+	return rankBy(input, branches, func(branch string) []string {
+		return []string{branch}
+	})
+}
+
+// rankBy returns the names for which one of the texts returned by texts fuzzy
+// matches input, best match first. Names are scored by their best matching
+// text.
+func rankBy(input string, names []string, texts func(name string) []string) []string {
+	if input == "" {
+		result := make([]string, len(names))
+		copy(result, names)
+		return result
+	}
+
+	type match struct {
+		name   string
+		score  int
+		length int
 	}
 
-	// Update the filter function and apply it
-	r.filter = filter
-	r.state.Branches = filter(r.state.Input)
+	matches := lo.FilterMap(names, func(name string, _ int) (match, bool) {
+		best := match{name: name}
+		found := false
+		for _, text := range texts(name) {
+			score, _, ok := fuzzyMatch(input, text)
+			if ok && (!found || score > best.score) {
+				best.score, best.length, found = score, len(text), true
+			}
+		}
+		return best, found
+	})
+
+	// Prefer shorter texts when two names score the same so that an exact
+	// match always comes before the names it prefixes.
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].length < matches[j].length
+	})
+
+	return lo.Map(matches, func(m match, _ int) string {
+		return m.name
+	})
+}
+
+func (r *Renderer) refreshBranchListWithSelection(targetBranch string, followBranch bool) {
+	r.state.Branches = r.filter(r.state.Input)
 
 	// Handle selection based on the followBranch parameter
 	if followBranch && targetBranch != "" {
@@ -0,0 +1,1267 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// harness drives a Renderer drawing on a simulation screen.
+type harness struct {
+	t        *testing.T
+	screen   tcell.SimulationScreen
+	renderer *Renderer
+	handler  SelectionHandler
+
+	// pinned holds the pinned branches.
+	pinned []string
+	// selection is what was selected, if anything.
+	selection *Selection
+	// pins and unpins record the branches the handler was asked to pin and
+	// unpin.
+	pins, unpins []string
+}
+
+// newHarness returns a harness for a renderer created with cfg on a 100x24
+// screen. Unset fields of cfg are given defaults suitable for tests.
+func newHarness(t *testing.T, cfg RendererConfig) *harness {
+	t.Helper()
+
+	h := &harness{t: t, screen: tcell.NewSimulationScreen("UTF-8")}
+
+	cfg.Screen = h.screen
+	if cfg.PinnedBranches == nil {
+		cfg.PinnedBranches = &h.pinned
+	}
+	if cfg.WindowSize == 0 {
+		cfg.WindowSize = 10
+	}
+	if cfg.PinnedBranchPrefix == "" {
+		cfg.PinnedBranchPrefix = "★"
+	}
+
+	renderer, err := NewRenderer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(renderer.Finish)
+
+	// Values loaded in the background are waited for with settle instead of
+	// the screen being told to redraw, which would leave an event in the way
+	// of the keys pressed next.
+	renderer.previews.notify = nil
+	renderer.aheadBehind.notify = nil
+	renderer.commits.notify = nil
+
+	h.renderer = renderer
+	h.handler = SelectionHandler{
+		OnSelect: func(selection Selection) {
+			h.selection = &selection
+		},
+		OnPin: func(branch string) error {
+			h.pins = append(h.pins, branch)
+			return nil
+		},
+		OnUnpin: func(branch string) error {
+			h.unpins = append(h.unpins, branch)
+			return nil
+		},
+	}
+
+	h.screen.SetSize(100, 24)
+	renderer.Draw()
+
+	return h
+}
+
+// branches returns local branches with the given names, each with an author
+// and subject so that columns can be displayed.
+func branches(names ...string) []git.Branch {
+	result := []git.Branch{}
+	for _, name := range names {
+		result = append(result, git.Branch{
+			Name:    name,
+			Ref:     "refs/heads/" + name,
+			Local:   true,
+			Author:  "Ada Lovelace",
+			Subject: "Work on " + name,
+		})
+	}
+
+	return result
+}
+
+// press sends keys to the renderer one at a time.
+func (h *harness) press(keys ...tcell.Key) {
+	h.t.Helper()
+
+	for _, key := range keys {
+		h.screen.InjectKey(key, 0, tcell.ModNone)
+		h.run()
+	}
+}
+
+// typeText types text into the renderer.
+func (h *harness) typeText(text string) {
+	h.t.Helper()
+
+	for _, ch := range text {
+		h.screen.InjectKey(tcell.KeyRune, ch, tcell.ModNone)
+		h.run()
+	}
+}
+
+// run handles the next event.
+func (h *harness) run() {
+	h.t.Helper()
+
+	if err := h.renderer.Run(h.handler); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// settle waits for the values loading in the background and redraws the
+// screen with them.
+func (h *harness) settle() {
+	h.t.Helper()
+
+	waitIdle(h.t, h.renderer.previews)
+	waitIdle(h.t, h.renderer.aheadBehind)
+	waitIdle(h.t, h.renderer.commits)
+	h.renderer.Draw()
+}
+
+// selected returns the name of the highlighted row.
+func (h *harness) selected() string {
+	return h.renderer.state.Branches[h.renderer.state.Selected]
+}
+
+// snapshot returns the text on the screen, without trailing spaces or
+// trailing empty lines.
+func (h *harness) snapshot() string {
+	cells, width, height := h.screen.GetContents()
+
+	lines := make([]string, height)
+	for row := range height {
+		var line strings.Builder
+		for _, cell := range cells[row*width : (row+1)*width] {
+			if len(cell.Runes) == 0 {
+				line.WriteRune(' ')
+				continue
+			}
+			line.WriteString(string(cell.Runes))
+		}
+		lines[row] = strings.TrimRight(line.String(), " ")
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// assertSnapshot compares the screen to testdata/name.golden. Run the tests
+// with -update to write the golden file instead.
+func (h *harness) assertSnapshot(name string) {
+	h.t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	got := h.snapshot()
+
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			h.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			h.t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+
+	if got != string(want) {
+		h.t.Errorf("screen doesn't match %v:\n--- got ---\n%v--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature/login", "feature/logout", "fix/typo"),
+		CurrentBranch: "main",
+		SearchLabel:   "search branch",
+		Columns:       []string{ColumnAuthor, ColumnSubject},
+	})
+
+	h.assertSnapshot("draw")
+}
+
+func TestFilter(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature/login", "feature/logout", "fix/typo"),
+		CurrentBranch: "main",
+		Columns:       []string{ColumnAuthor, ColumnSubject},
+	})
+
+	h.typeText("log")
+
+	want := []string{"feature/login", "feature/logout"}
+	if got := h.renderer.state.Branches; !slices.Equal(got, want) {
+		t.Errorf("branches = %v, want %v", got, want)
+	}
+
+	h.assertSnapshot("filter")
+
+	h.press(tcell.KeyBackspace2, tcell.KeyBackspace2, tcell.KeyBackspace2)
+
+	if got := len(h.renderer.state.Branches); got != 4 {
+		t.Errorf("%v branches listed after clearing the search, want 4", got)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches: branches("a", "b", "c"),
+	})
+
+	h.press(tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyUp, tcell.KeyEnter)
+
+	if h.selection == nil {
+		t.Fatal("nothing was selected")
+	}
+
+	if h.selection.Branch.Name != "b" {
+		t.Errorf("selected %q, want %q", h.selection.Branch.Name, "b")
+	}
+
+	if !h.renderer.IsDone() {
+		t.Error("renderer isn't done after selecting a branch")
+	}
+}
+
+func TestQuit(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches: branches("a", "b"),
+	})
+
+	h.press(tcell.KeyEsc)
+
+	if !h.renderer.IsDone() {
+		t.Error("renderer isn't done after pressing escape")
+	}
+
+	if h.selection != nil {
+		t.Errorf("selected %q after pressing escape", h.selection.Branch.Name)
+	}
+}
+
+func TestScroll(t *testing.T) {
+	names := []string{}
+	for _, ch := range "abcdefghijkl" {
+		names = append(names, "branch-"+string(ch))
+	}
+
+	h := newHarness(t, RendererConfig{
+		Branches:   branches(names...),
+		WindowSize: 5,
+		Columns:    []string{ColumnSubject},
+	})
+
+	for range 7 {
+		h.press(tcell.KeyDown)
+	}
+
+	if got := h.selected(); got != "branch-h" {
+		t.Errorf("selected %q, want %q", got, "branch-h")
+	}
+
+	if got := h.renderer.state.WindowStart; got != 3 {
+		t.Errorf("window starts at %v, want 3", got)
+	}
+
+	h.assertSnapshot("scroll")
+
+	// Moving up within the window doesn't scroll it.
+	h.press(tcell.KeyUp, tcell.KeyUp)
+
+	if got := h.renderer.state.WindowStart; got != 3 {
+		t.Errorf("window starts at %v after moving up, want 3", got)
+	}
+
+	// Pressing down at the bottom of the list stays on the last branch.
+	for range 20 {
+		h.press(tcell.KeyDown)
+	}
+
+	if got := h.selected(); got != "branch-l" {
+		t.Errorf("selected %q, want %q", got, "branch-l")
+	}
+}
+
+func TestPinAndUnpin(t *testing.T) {
+	tests := []struct {
+		name string
+		// updatesPins is whether the handler updates the pinned branches
+		// itself, like the one of the branch selector does.
+		updatesPins bool
+	}{
+		{"renderer updates pins", false},
+		{"handler updates pins", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, RendererConfig{
+				Branches: branches("a", "b", "c"),
+				Columns:  []string{ColumnSubject},
+			})
+
+			if tt.updatesPins {
+				h.handler.OnPin = func(branch string) error {
+					h.pins = append(h.pins, branch)
+					h.pinned = append(h.pinned, branch)
+					return nil
+				}
+				h.handler.OnUnpin = func(branch string) error {
+					h.unpins = append(h.unpins, branch)
+					h.pinned = slices.DeleteFunc(slices.Clone(h.pinned), func(pin string) bool {
+						return pin == branch
+					})
+					return nil
+				}
+			}
+
+			h.press(tcell.KeyDown, tcell.KeyDown, tcell.KeyCtrlD)
+
+			if !slices.Equal(h.pins, []string{"c"}) || !slices.Equal(h.pinned, []string{"c"}) {
+				t.Errorf("pinned %v and handler asked to pin %v, want c", h.pinned, h.pins)
+			}
+
+			// The pinned branch moves to the top and stays selected.
+			if want := []string{"c", "a", "b"}; !slices.Equal(h.renderer.state.Branches, want) {
+				t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+			}
+
+			if got := h.selected(); got != "c" {
+				t.Errorf("selected %q, want %q", got, "c")
+			}
+
+			h.assertSnapshot("pin")
+
+			// Pinning it again does nothing.
+			h.press(tcell.KeyCtrlD)
+
+			if !slices.Equal(h.pins, []string{"c"}) {
+				t.Errorf("handler asked to pin %v, want c once", h.pins)
+			}
+
+			// The unpinned branch goes back in place, and the branch after
+			// it in the list is selected.
+			h.press(tcell.KeyCtrlU)
+
+			if !slices.Equal(h.unpins, []string{"c"}) || len(h.pinned) != 0 {
+				t.Errorf("pinned %v and handler asked to unpin %v, want c unpinned", h.pinned, h.unpins)
+			}
+
+			if want := []string{"a", "b", "c"}; !slices.Equal(h.renderer.state.Branches, want) {
+				t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+			}
+
+			if got := h.selected(); got != "a" {
+				t.Errorf("selected %q, want %q", got, "a")
+			}
+
+			// Unpinning a branch that isn't pinned does nothing.
+			h.press(tcell.KeyCtrlU)
+
+			if !slices.Equal(h.unpins, []string{"c"}) || h.selected() != "a" {
+				t.Errorf("handler asked to unpin %v with %q selected, want c once with a selected", h.unpins, h.selected())
+			}
+		})
+	}
+}
+
+func TestCreateBranch(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:    branches("main", "develop"),
+		AllowCreate: true,
+	})
+
+	h.typeText("new")
+
+	h.assertSnapshot("create")
+
+	h.press(tcell.KeyEnter)
+
+	if h.selection == nil || !h.selection.Create || h.selection.Branch.Name != "new" {
+		t.Errorf("selection = %+v, want to create new", h.selection)
+	}
+}
+
+func TestHideAndReveal(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:       branches("main", "dependabot/npm", "feature", "old"),
+		CurrentBranch:  "main",
+		HiddenBranches: []string{"dependabot/npm"},
+		Columns:        []string{ColumnSubject},
+	})
+
+	var hidden, unhidden []string
+	h.handler.OnHide = func(branch string) error {
+		hidden = append(hidden, branch)
+		return nil
+	}
+	h.handler.OnUnhide = func(branch string) error {
+		unhidden = append(unhidden, branch)
+		return nil
+	}
+
+	if want := []string{"feature", "main", "old"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+
+	// The current branch can't be hidden.
+	h.press(tcell.KeyDown, tcell.KeyCtrlK)
+
+	if len(hidden) != 0 {
+		t.Errorf("handler asked to hide %v, want nothing hidden", hidden)
+	}
+
+	// Hiding a branch selects the one after it.
+	h.press(tcell.KeyDown, tcell.KeyCtrlK)
+
+	if !slices.Equal(hidden, []string{"old"}) {
+		t.Errorf("handler asked to hide %v, want old", hidden)
+	}
+
+	if want := []string{"feature", "main"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+
+	if got := h.selected(); got != "main" {
+		t.Errorf("selected %q, want %q", got, "main")
+	}
+
+	h.press(tcell.KeyCtrlT)
+
+	if want := []string{"dependabot/npm", "feature", "main", "old"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v with hidden branches shown, want %v", h.renderer.state.Branches, want)
+	}
+
+	h.assertSnapshot("hidden")
+
+	h.press(tcell.KeyUp, tcell.KeyUp, tcell.KeyCtrlK, tcell.KeyCtrlT)
+
+	if !slices.Equal(unhidden, []string{"dependabot/npm"}) {
+		t.Errorf("handler asked to unhide %v, want dependabot/npm", unhidden)
+	}
+
+	if want := []string{"dependabot/npm", "feature", "main"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+}
+
+func TestDeleteBranch(t *testing.T) {
+	branchList := branches("main", "feature", "fix")
+	branchList[1].Remotes = []string{"origin"}
+
+	h := newHarness(t, RendererConfig{
+		Branches:      branchList,
+		CurrentBranch: "main",
+		Columns:       []string{ColumnSubject},
+	})
+
+	var deleted, deletedRemote []string
+	h.handler.OnDelete = func(branch string, force bool) error {
+		if force {
+			t.Errorf("%v deleted with force", branch)
+		}
+		deleted = append(deleted, branch)
+		return nil
+	}
+	h.handler.OnDeleteRemote = func(remote, branch string) error {
+		deletedRemote = append(deletedRemote, remote+"/"+branch)
+		return nil
+	}
+
+	// The current branch can't be deleted.
+	h.press(tcell.KeyDown, tcell.KeyDown, tcell.KeyCtrlX)
+
+	if h.renderer.state.Prompt != nil || !strings.Contains(h.renderer.state.Message, "checked out") {
+		t.Errorf("message = %q, want the current branch not to be deleted", h.renderer.state.Message)
+	}
+
+	h.press(tcell.KeyUp, tcell.KeyUp, tcell.KeyCtrlX)
+
+	h.assertSnapshot("delete")
+
+	h.typeText("y")
+
+	if !slices.Equal(deleted, []string{"feature"}) {
+		t.Errorf("deleted %v, want feature", deleted)
+	}
+
+	// The branch is still listed from its remote, which is offered to be
+	// deleted next.
+	if want := []string{"feature", "fix", "main"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+	if h.renderer.state.Prompt == nil || !strings.Contains(h.renderer.state.Prompt.message, "from origin too?") {
+		t.Fatalf("prompt = %+v, want to delete feature from origin", h.renderer.state.Prompt)
+	}
+
+	h.typeText("y")
+
+	if !slices.Equal(deletedRemote, []string{"origin/feature"}) {
+		t.Errorf("deleted %v from remotes, want origin/feature", deletedRemote)
+	}
+
+	if want := []string{"fix", "main"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+	if h.selected() != "fix" {
+		t.Errorf("selected %q, want fix", h.selected())
+	}
+}
+
+func TestForceDeleteUnmergedBranch(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature"),
+		CurrentBranch: "main",
+	})
+
+	var forced []bool
+	h.handler.OnDelete = func(branch string, force bool) error {
+		forced = append(forced, force)
+		if !force {
+			return git.ErrBranchNotMerged
+		}
+		return nil
+	}
+
+	h.press(tcell.KeyCtrlX)
+	h.typeText("y")
+
+	if h.renderer.state.Prompt == nil || !strings.Contains(h.renderer.state.Prompt.message, "not fully merged") {
+		t.Fatalf("prompt = %+v, want to delete feature anyway", h.renderer.state.Prompt)
+	}
+
+	h.assertSnapshot("delete-unmerged")
+
+	h.typeText("y")
+
+	if !slices.Equal(forced, []bool{false, true}) {
+		t.Errorf("deleted with force %v, want without and then with force", forced)
+	}
+
+	if want := []string{"main"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+}
+
+func TestDeleteRemoteBranch(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches: []git.Branch{
+			{Name: "main", Ref: "refs/heads/main", Local: true},
+			{Name: "feature", Ref: "refs/remotes/origin/feature", Remotes: []string{"origin", "upstream"}},
+		},
+		CurrentBranch: "main",
+	})
+
+	h.handler.OnDelete = func(branch string, force bool) error {
+		t.Errorf("deleted local branch %v", branch)
+		return nil
+	}
+
+	var deletedRemote []string
+	h.handler.OnDeleteRemote = func(remote, branch string) error {
+		deletedRemote = append(deletedRemote, remote+"/"+branch)
+		if remote == "upstream" {
+			return errors.New("permission denied")
+		}
+		return nil
+	}
+
+	// Branches on several remotes ask which remote to delete them from.
+	h.press(tcell.KeyCtrlX)
+
+	h.assertSnapshot("delete-remote")
+
+	h.typeText("1")
+
+	if !slices.Equal(deletedRemote, []string{"origin/feature"}) {
+		t.Errorf("deleted %v from remotes, want origin/feature", deletedRemote)
+	}
+
+	// The branch is still on upstream, which is now the only remote
+	// offered.
+	if branch, _ := h.renderer.branch("feature"); branch.Ref != "refs/remotes/upstream/feature" {
+		t.Errorf("feature ref = %v, want refs/remotes/upstream/feature", branch.Ref)
+	}
+
+	h.press(tcell.KeyCtrlX)
+
+	if h.renderer.state.Prompt == nil || !strings.Contains(h.renderer.state.Prompt.message, "from upstream too?") {
+		t.Fatalf("prompt = %+v, want to delete feature from upstream", h.renderer.state.Prompt)
+	}
+
+	h.typeText("y")
+
+	if h.renderer.state.Message != "error: permission denied" {
+		t.Errorf("message = %q, want the error", h.renderer.state.Message)
+	}
+	if want := []string{"feature", "main"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+}
+
+func TestPatternPins(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:         branches("main", "release/2.0", "hotfix/12", "release/1.0", "feature", "hotfix/x"),
+		Columns:          []string{ColumnSubject},
+		PinnedBranches:   &[]string{`re:^hotfix/\d+$`, "main", "release/*", "release/2.0"},
+		PatternPinPrefix: "☆",
+	})
+
+	// Pins keep their order, with the branches matching a pattern sorted
+	// and exact pins taking precedence over patterns.
+	want := []string{"hotfix/12", "main", "release/1.0", "release/2.0", "feature", "hotfix/x"}
+	if got := h.renderer.state.Branches; !slices.Equal(got, want) {
+		t.Errorf("branches = %v, want %v", got, want)
+	}
+
+	h.assertSnapshot("pattern-pins")
+
+	// Branches pinned by a pattern can't be unpinned on their own.
+	h.press(tcell.KeyCtrlU)
+
+	if len(h.unpins) != 0 {
+		t.Errorf("handler asked to unpin %v, want nothing unpinned", h.unpins)
+	}
+
+	if !strings.Contains(h.renderer.state.Message, "re:^hotfix/") {
+		t.Errorf("message = %q, want it to name the pattern", h.renderer.state.Message)
+	}
+}
+
+func TestRegexpPinMatchesPrefix(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:       branches("main", "hotfix/12-login", "hotfix/x", "old/hotfix/3"),
+		PinnedBranches: &[]string{`re:^hotfix/\d+`},
+	})
+
+	// Regular expressions match anywhere unless anchored, like in the README.
+	want := []string{"hotfix/12-login", "hotfix/x", "main", "old/hotfix/3"}
+	if got := h.renderer.state.Branches; !slices.Equal(got, want) {
+		t.Errorf("branches = %v, want %v", got, want)
+	}
+
+	if got := h.renderer.numberedBranches(); !slices.Equal(got, []string{"hotfix/12-login"}) {
+		t.Errorf("numbered branches = %v, want hotfix/12-login", got)
+	}
+}
+
+func TestMovePin(t *testing.T) {
+	pinned := []string{"main", "release/*", "develop"}
+	h := newHarness(t, RendererConfig{
+		Branches:       branches("main", "develop", "release/1.0", "release/2.0", "feature"),
+		Columns:        []string{ColumnSubject},
+		PinnedBranches: &pinned,
+	})
+
+	type move struct {
+		pin      string
+		position int
+	}
+	var moves []move
+	h.handler.OnMovePin = func(pin string, position int) error {
+		moves = append(moves, move{pin, position})
+		return nil
+	}
+
+	shift := func(key tcell.Key) {
+		h.screen.InjectKey(key, 0, tcell.ModShift)
+		h.run()
+	}
+
+	// Moving develop up moves it past every branch matching release/*.
+	h.press(tcell.KeyDown, tcell.KeyDown, tcell.KeyDown)
+	shift(tcell.KeyUp)
+
+	if want := []string{"main", "develop", "release/*"}; !slices.Equal(pinned, want) {
+		t.Errorf("pinned = %v, want %v", pinned, want)
+	}
+
+	if want := []string{"main", "develop", "release/1.0", "release/2.0", "feature"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+
+	if got := h.selected(); got != "develop" {
+		t.Errorf("selected %q, want %q", got, "develop")
+	}
+
+	// Moving a branch pinned by a pattern moves the pattern, and pins can't
+	// be moved past the first or last pin.
+	h.press(tcell.KeyDown, tcell.KeyDown)
+	shift(tcell.KeyUp)
+	shift(tcell.KeyUp)
+	shift(tcell.KeyUp)
+
+	if want := []string{"release/*", "main", "develop"}; !slices.Equal(pinned, want) {
+		t.Errorf("pinned = %v, want %v", pinned, want)
+	}
+
+	want := []move{{"develop", 2}, {"release/*", 2}, {"release/*", 1}}
+	if !slices.Equal(moves, want) {
+		t.Errorf("handler asked for moves %v, want %v", moves, want)
+	}
+
+	// Branches that aren't pinned can't be moved.
+	h.press(tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown)
+	shift(tcell.KeyDown)
+
+	if !strings.Contains(h.renderer.state.Message, "isn't pinned") {
+		t.Errorf("message = %q, want it to say the branch isn't pinned", h.renderer.state.Message)
+	}
+}
+
+func TestQuickSelect(t *testing.T) {
+	tests := []struct {
+		name        string
+		quickSelect string
+		input       string
+		key         rune
+		mod         tcell.ModMask
+		want        string
+		wantInput   string
+	}{
+		{"alt", QuickSelectAlt, "", '2', tcell.ModAlt, "feature", ""},
+		{"alt while searching", QuickSelectAlt, "zzz", '1', tcell.ModAlt, "main", "zzz"},
+		{"digit", QuickSelectDigit, "", '2', tcell.ModNone, "feature", ""},
+		{"digit is typed in alt mode", QuickSelectAlt, "", '2', tcell.ModNone, "", "2"},
+		{"digit is typed while searching", QuickSelectDigit, "f", '2', tcell.ModNone, "", "f2"},
+		{"digit without such pin is typed", QuickSelectDigit, "", '3', tcell.ModNone, "", "3"},
+		{"no such pin", QuickSelectAlt, "", '3', tcell.ModAlt, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, RendererConfig{
+				Branches:       branches("main", "feature", "fix"),
+				PinnedBranches: &[]string{"main", "feature"},
+				QuickSelect:    tt.quickSelect,
+			})
+
+			h.typeText(tt.input)
+			h.screen.InjectKey(tcell.KeyRune, tt.key, tt.mod)
+			h.run()
+
+			var got string
+			if h.selection != nil {
+				got = h.selection.Branch.Name
+			}
+
+			if got != tt.want {
+				t.Errorf("selected %q, want %q", got, tt.want)
+			}
+
+			if h.renderer.state.Input != tt.wantInput {
+				t.Errorf("input = %q, want %q", h.renderer.state.Input, tt.wantInput)
+			}
+		})
+	}
+}
+
+func TestWorktrees(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature", "fix"),
+		CurrentBranch: "main",
+		Columns:       []string{ColumnSubject},
+		Worktrees:     map[string]string{"feature": "/src/repo-feature"},
+		WorktreePath: func(branch string) string {
+			return "/src/repo-" + branch
+		},
+	})
+
+	h.assertSnapshot("worktrees")
+
+	// Selecting a branch checked out in another worktree offers to go there.
+	h.press(tcell.KeyEnter)
+
+	if h.selection != nil {
+		t.Fatalf("selected %+v without asking", h.selection)
+	}
+
+	h.typeText("g")
+
+	if h.selection == nil || h.selection.Worktree != "/src/repo-feature" || h.selection.CreateWorktree {
+		t.Errorf("selection = %+v, want to go to /src/repo-feature", h.selection)
+	}
+}
+
+func TestCreateWorktree(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature", "fix"),
+		CurrentBranch: "main",
+		Worktrees:     map[string]string{"feature": "/src/repo-feature"},
+		WorktreePath: func(branch string) string {
+			return "/src/repo-" + branch
+		},
+	})
+
+	// Branches that are checked out can't get another worktree.
+	h.press(tcell.KeyCtrlW)
+
+	if h.renderer.state.Prompt != nil || !strings.Contains(h.renderer.state.Message, "already checked out") {
+		t.Errorf("message = %q, want it to say feature is already checked out", h.renderer.state.Message)
+	}
+
+	h.press(tcell.KeyDown, tcell.KeyCtrlW)
+	h.typeText("y")
+
+	want := Selection{Branch: branches("fix")[0], Worktree: "/src/repo-fix", CreateWorktree: true}
+	if h.selection == nil || !reflect.DeepEqual(*h.selection, want) {
+		t.Errorf("selection = %+v, want %+v", h.selection, want)
+	}
+}
+
+func TestLocalChanges(t *testing.T) {
+	tests := []struct {
+		key       rune
+		wantStash bool
+	}{
+		{'s', true},
+		{'c', false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.key), func(t *testing.T) {
+			h := newHarness(t, RendererConfig{
+				Branches:      branches("main", "feature"),
+				CurrentBranch: "main",
+				LocalChanges:  true,
+			})
+
+			h.press(tcell.KeyEnter)
+
+			if h.selection != nil || h.renderer.state.Prompt == nil {
+				t.Fatalf("selected %+v without asking about the changes", h.selection)
+			}
+
+			if tt.key == 's' {
+				h.assertSnapshot("local-changes")
+			}
+
+			h.typeText(string(tt.key))
+
+			if h.selection == nil || h.selection.Branch.Name != "feature" || h.selection.Stash != tt.wantStash {
+				t.Errorf("selection = %+v, want feature with Stash %v", h.selection, tt.wantStash)
+			}
+		})
+	}
+
+	// Cancelling the prompt doesn't switch.
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature"),
+		CurrentBranch: "main",
+		LocalChanges:  true,
+	})
+
+	h.press(tcell.KeyEnter, tcell.KeyEsc)
+
+	if h.selection != nil || h.renderer.IsDone() {
+		t.Errorf("selected %+v after cancelling", h.selection)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature"),
+		CurrentBranch: "main",
+		Status:        &git.Status{Staged: 2, Unstaged: 1, Untracked: 3, Operation: git.OperationRebase},
+	})
+
+	h.assertSnapshot("status")
+
+	// Switching away during a rebase asks for confirmation.
+	h.press(tcell.KeyEnter)
+
+	if h.selection != nil {
+		t.Fatalf("selected %+v without a warning", h.selection)
+	}
+
+	h.typeText("y")
+
+	if h.selection == nil || h.selection.Branch.Name != "feature" {
+		t.Errorf("selection = %+v, want feature", h.selection)
+	}
+}
+
+func TestStatusSummary(t *testing.T) {
+	tests := []struct {
+		status git.Status
+		want   string
+	}{
+		{git.Status{}, "clean"},
+		{git.Status{Untracked: 4}, "4 untracked"},
+		{git.Status{Staged: 1, Unstaged: 2, Conflicted: 3}, "3 conflicted, 1 staged, 2 unstaged"},
+	}
+
+	for _, tt := range tests {
+		if got := statusSummary(tt.status); got != tt.want {
+			t.Errorf("statusSummary(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:     branches("main", "v2.0"),
+		DetachedHead: "0123456 (v1.0-1-g0123456)",
+		Tags: []git.Branch{
+			{Name: "v1.0", Ref: "refs/tags/v1.0", Author: "Ada Lovelace", Subject: "Release 1.0"},
+			{Name: "v2.0", Ref: "refs/tags/v2.0", Author: "Ada Lovelace", Subject: "Release 2.0"},
+		},
+		Columns: []string{ColumnAuthor, ColumnSubject},
+	})
+	h.pinned = []string{"v2.0"}
+
+	h.press(tcell.KeyCtrlG)
+	h.typeText("2")
+	h.assertSnapshot("tags")
+
+	// Branch hotkeys do nothing while tags are listed.
+	h.press(tcell.KeyCtrlD)
+
+	if len(h.pins) != 0 {
+		t.Errorf("pinned %v while tags are listed", h.pins)
+	}
+
+	h.press(tcell.KeyEnter)
+
+	if h.selection == nil || h.selection.Branch.Ref != "refs/tags/v2.0" || !h.selection.Detach {
+		t.Errorf("selection = %+v, want v2.0 detached", h.selection)
+	}
+}
+
+// commits returns the commits listed when browsing the commits on a branch.
+func commits() []git.Branch {
+	return []git.Branch{
+		{Name: "3333333", Ref: "3333333333333333333333333333333333333333", Hash: "3333333333333333333333333333333333333333", Author: "Ada Lovelace", Subject: "Fix the login form"},
+		{Name: "2222222", Ref: "2222222222222222222222222222222222222222", Hash: "2222222222222222222222222222222222222222", Author: "Charles Babbage", Subject: "Add the difference engine"},
+		{Name: "1111111", Ref: "1111111111111111111111111111111111111111", Hash: "1111111111111111111111111111111111111111", Author: "Ada Lovelace", Subject: "Initial commit"},
+	}
+}
+
+func TestBrowseCommits(t *testing.T) {
+	var browsed string
+	release := make(chan struct{})
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature"),
+		CurrentBranch: "main",
+		Commits: func(branch git.Branch) ([]git.Branch, error) {
+			<-release
+			browsed = branch.Ref
+			return commits(), nil
+		},
+	})
+
+	h.press(tcell.KeyDown, tcell.KeyCtrlL)
+
+	// The commits load in the background.
+	if !strings.Contains(h.snapshot(), "loading commits...") {
+		t.Errorf("commits aren't shown as loading:\n%v", h.snapshot())
+	}
+
+	close(release)
+	h.settle()
+
+	if browsed != "refs/heads/main" {
+		t.Fatalf("browsed %q, want refs/heads/main", browsed)
+	}
+
+	h.typeText("engine")
+	h.assertSnapshot("commits")
+
+	// Esc goes back to the branches as they were.
+	h.press(tcell.KeyEsc)
+
+	if h.renderer.IsDone() || h.selected() != "main" || h.renderer.state.Input != "" {
+		t.Errorf("selected %q with input %q after leaving the commits, want main", h.selected(), h.renderer.state.Input)
+	}
+}
+
+func TestRankCommitsNarrowsSearch(t *testing.T) {
+	log := &commitLog{commits: commits()}
+
+	// Each search ranks the same as it would from scratch, whether it
+	// extends the last one or not.
+	for _, input := range []string{"", "a", "ad", "ada", "ad", "e", "en", "eng", "in", "init"} {
+		if got, want := log.rank(input), rankCommits(input, commits()); !slices.Equal(got, want) {
+			t.Errorf("rank(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	if len(log.matched) != 1 || log.matched[0].Name != "1111111" {
+		t.Errorf("matched = %+v after searching for init, want the initial commit", log.matched)
+	}
+}
+
+func TestBrowseCommitsError(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches: branches("main", "feature"),
+		Commits: func(git.Branch) ([]git.Branch, error) {
+			return nil, errors.New("bad revision")
+		},
+	})
+
+	h.press(tcell.KeyDown, tcell.KeyCtrlL)
+	h.settle()
+
+	if h.renderer.state.Mode != modeBranches || h.selected() != "main" || h.renderer.state.Message != "bad revision" {
+		t.Errorf("mode %q with %q selected and message %q, want back on main with the error", h.renderer.state.Mode, h.selected(), h.renderer.state.Message)
+	}
+}
+
+func TestCheckOutCommit(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches: branches("main"),
+		Commits: func(git.Branch) ([]git.Branch, error) {
+			return commits(), nil
+		},
+	})
+
+	h.press(tcell.KeyCtrlL)
+	h.settle()
+	h.press(tcell.KeyDown, tcell.KeyEnter)
+	h.typeText("d")
+
+	if h.selection == nil || !h.selection.Detach || h.selection.Branch.Ref != commits()[1].Hash {
+		t.Errorf("selection = %+v, want 2222222 detached", h.selection)
+	}
+}
+
+func TestCreateBranchAtCommit(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:    branches("main", "feature"),
+		AllowCreate: true,
+		Commits: func(git.Branch) ([]git.Branch, error) {
+			return commits(), nil
+		},
+	})
+
+	h.press(tcell.KeyCtrlL)
+	h.settle()
+	h.press(tcell.KeyEnter)
+	h.typeText("b")
+
+	// Existing branches can't be created again.
+	h.typeText("feature")
+	h.press(tcell.KeyEnter)
+
+	if h.selection != nil || !strings.Contains(h.renderer.state.Message, "already exists") {
+		t.Fatalf("selection = %+v with message %q, want an error", h.selection, h.renderer.state.Message)
+	}
+
+	h.press(tcell.KeyEnter)
+	h.typeText("bx")
+	h.press(tcell.KeyBackspace2)
+	h.typeText("fix")
+	h.press(tcell.KeyEnter)
+
+	want := Selection{
+		Branch: git.Branch{Name: "fix", Local: true},
+		Create: true,
+		Base:   commits()[0].Hash,
+	}
+	if h.selection == nil || !reflect.DeepEqual(*h.selection, want) {
+		t.Errorf("selection = %+v, want %+v", h.selection, want)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	var loaded []string
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature", "empty"),
+		CurrentBranch: "main",
+		Tags:          []git.Branch{{Name: "feature", Ref: "refs/tags/feature"}},
+		ShowPreview:   true,
+		Preview: func(branch git.Branch) ([]string, error) {
+			loaded = append(loaded, branch.Ref)
+			if branch.Name == "empty" {
+				return nil, nil
+			}
+			return []string{"1234567 Work on " + branch.Ref}, nil
+		},
+	})
+
+	if !strings.Contains(h.snapshot(), "loading...") {
+		t.Errorf("preview doesn't say it is loading:\n%v", h.snapshot())
+	}
+
+	h.settle()
+	if !strings.Contains(h.snapshot(), "no commits that aren't on main") {
+		t.Errorf("preview of a branch without commits of its own:\n%v", h.snapshot())
+	}
+
+	h.press(tcell.KeyDown)
+	h.settle()
+	h.assertSnapshot("preview")
+
+	// A tag named like a branch has its own preview.
+	h.press(tcell.KeyCtrlG)
+	h.settle()
+	if !strings.Contains(h.snapshot(), "Work on refs/tags/feature") {
+		t.Errorf("preview of the feature tag:\n%v", h.snapshot())
+	}
+
+	// Going back to a branch uses the preview loaded before.
+	h.press(tcell.KeyCtrlG)
+	h.settle()
+	want := []string{"refs/heads/empty", "refs/heads/feature", "refs/tags/feature"}
+	if !slices.Equal(loaded, want) {
+		t.Errorf("loaded previews for %v, want %v", loaded, want)
+	}
+}
+
+func TestPreviewDetached(t *testing.T) {
+	h := newHarness(t, RendererConfig{
+		Branches:     branches("main"),
+		DetachedHead: "1234567 (v1.0)",
+		ShowPreview:  true,
+		Preview: func(git.Branch) ([]string, error) {
+			return nil, nil
+		},
+	})
+
+	h.settle()
+	if !strings.Contains(h.snapshot(), "no commits that aren't on 1234567 (v1.0)") {
+		t.Errorf("preview with HEAD detached:\n%v", h.snapshot())
+	}
+}
+
+func TestAheadBehindColumns(t *testing.T) {
+	list := branches("main", "feature", "fix", "old", "stale")
+	list[1].Upstream = "origin/feature"
+	list[2].Upstream = "origin/fix"
+
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	h := newHarness(t, RendererConfig{
+		Branches:      list,
+		CurrentBranch: "main",
+		WindowSize:    3,
+		Columns:       []string{ColumnAheadBehind, ColumnDivergence},
+		AheadBehind: func(ref, base string) (git.AheadBehind, error) {
+			mu.Lock()
+			requested = append(requested, ref+"..."+base)
+			mu.Unlock()
+
+			switch {
+			case base == "origin/fix":
+				return git.AheadBehind{}, errors.New("no such upstream")
+			case base == "main":
+				return git.AheadBehind{Ahead: 1, Behind: 12}, nil
+			}
+			return git.AheadBehind{Ahead: 3}, nil
+		},
+	})
+
+	if !strings.Contains(h.snapshot(), "…") {
+		t.Errorf("counts aren't shown as loading:\n%v", h.snapshot())
+	}
+
+	h.settle()
+	h.assertSnapshot("ahead-behind")
+
+	// Only the visible branches are counted, and the current branch isn't
+	// compared with itself.
+	mu.Lock()
+	slices.Sort(requested)
+	want := []string{
+		"refs/heads/feature...main",
+		"refs/heads/feature...origin/feature",
+		"refs/heads/fix...main",
+		"refs/heads/fix...origin/fix",
+	}
+	if !slices.Equal(requested, want) {
+		t.Errorf("counted %v, want %v", requested, want)
+	}
+	mu.Unlock()
+}
+
+func TestSortAheadBehind(t *testing.T) {
+	list := branches("a", "b", "c", "d")
+	for i := range list {
+		list[i].Upstream = "origin/" + list[i].Name
+	}
+	ahead := map[string]int{"refs/heads/a": 1, "refs/heads/b": 0, "refs/heads/c": 3, "refs/heads/d": 2}
+
+	h := newHarness(t, RendererConfig{
+		Branches:   list,
+		WindowSize: 2,
+		AheadBehind: func(ref, base string) (git.AheadBehind, error) {
+			return git.AheadBehind{Ahead: ahead[ref]}, nil
+		},
+	})
+
+	h.press(tcell.KeyDown)
+	for h.renderer.state.Sort != SortAheadBehind {
+		h.press(tcell.KeyCtrlO)
+	}
+	h.settle()
+
+	// Every branch is counted to be sorted, not only the visible ones, and
+	// the selected branch stays selected.
+	if want := []string{"c", "d", "a", "b"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("branches = %v, want %v", h.renderer.state.Branches, want)
+	}
+	if h.selected() != "b" {
+		t.Errorf("selected %q, want b", h.selected())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	h := newHarness(t, RendererConfig{
+		Branches:      branches("main", "feature", "fix"),
+		CurrentBranch: "main",
+		History:       []string{"feature", commit, "gone", "main", "fix"},
+		Columns:       []string{ColumnAuthor, ColumnSubject},
+	})
+
+	h.press(tcell.KeyCtrlR)
+
+	// Deleted branches and the current branch are left out, while the
+	// commit HEAD was detached at is listed by its abbreviated hash.
+	if want := []string{"feature", "0123456", "fix"}; !slices.Equal(h.renderer.state.Branches, want) {
+		t.Errorf("history = %v, want %v", h.renderer.state.Branches, want)
+	}
+	h.assertSnapshot("history")
+
+	// Branch hotkeys do nothing on a commit.
+	h.press(tcell.KeyDown, tcell.KeyCtrlD)
+
+	if len(h.pins) != 0 {
+		t.Errorf("pinned %v from the history", h.pins)
+	}
+
+	h.press(tcell.KeyEnter)
+
+	if h.selection == nil || h.selection.Branch.Ref != commit || !h.selection.Detach {
+		t.Errorf("selection = %+v, want %v detached", h.selection, commit)
+	}
+}
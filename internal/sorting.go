@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// Sort modes for the branch list.
+const (
+	SortAlphabetical = "alphabetical"
+	SortCommitted    = "committed"
+	SortCheckout     = "checkout"
+	SortAuthor       = "author"
+	// SortAheadBehind puts the branches with the most commits ahead of their
+	// upstream first, then those with the most commits behind it.
+	SortAheadBehind = "ahead-behind"
+)
+
+// SortModes lists every sort mode in the order they are cycled through.
+var SortModes = []string{
+	SortAlphabetical,
+	SortCommitted,
+	SortCheckout,
+	SortAuthor,
+	SortAheadBehind,
+}
+
+// nextSortMode returns the sort mode that follows mode.
+func nextSortMode(mode string) string {
+	idx := lo.IndexOf(SortModes, mode)
+	return SortModes[(idx+1)%len(SortModes)]
+}
+
+// sortBranches returns a copy of branches sorted according to mode.
+// recentlyCheckedOut lists branch names, most recently checked out first, and
+// is only used by SortCheckout. counts returns how far a branch is ahead of
+// and behind its upstream, or false if that isn't known, and is only used by
+// SortAheadBehind.
+func sortBranches(mode string, branches []git.Branch, recentlyCheckedOut []string, counts func(git.Branch) (git.AheadBehind, bool)) []git.Branch {
+	sorted := make([]git.Branch, len(branches))
+	copy(sorted, branches)
+
+	byName := func(a, b git.Branch) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+
+	byCommitted := func(a, b git.Branch) bool {
+		if !a.CommitterDate.Equal(b.CommitterDate) {
+			return a.CommitterDate.After(b.CommitterDate)
+		}
+		return byName(a, b)
+	}
+
+	var less func(a, b git.Branch) bool
+	switch mode {
+	case SortCommitted:
+		less = byCommitted
+	case SortCheckout:
+		less = func(a, b git.Branch) bool {
+			ai := lo.IndexOf(recentlyCheckedOut, a.Name)
+			bi := lo.IndexOf(recentlyCheckedOut, b.Name)
+			switch {
+			case ai != -1 && bi != -1:
+				return ai < bi
+			case ai != -1:
+				return true
+			case bi != -1:
+				return false
+			}
+			// Branches that have never been checked out fall back to the
+			// order they were committed in.
+			return byCommitted(a, b)
+		}
+	case SortAuthor:
+		less = func(a, b git.Branch) bool {
+			if !strings.EqualFold(a.Author, b.Author) {
+				return strings.ToLower(a.Author) < strings.ToLower(b.Author)
+			}
+			return byCommitted(a, b)
+		}
+	case SortAheadBehind:
+		known := map[string]git.AheadBehind{}
+		for _, b := range sorted {
+			if counts == nil {
+				break
+			}
+			if c, ok := counts(b); ok {
+				known[b.Ref] = c
+			}
+		}
+
+		less = func(a, b git.Branch) bool {
+			ac, aok := known[a.Ref]
+			bc, bok := known[b.Ref]
+			switch {
+			case aok && bok && ac != bc:
+				if ac.Ahead != bc.Ahead {
+					return ac.Ahead > bc.Ahead
+				}
+				return ac.Behind > bc.Behind
+			case aok != bok:
+				return aok
+			}
+			// Branches without an upstream, or whose counts are still
+			// loading, fall back to the order they were committed in.
+			return byCommitted(a, b)
+		}
+	default:
+		less = byName
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+
+	return sorted
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+func TestNextSortMode(t *testing.T) {
+	tests := []struct {
+		mode, want string
+	}{
+		{SortAlphabetical, SortCommitted},
+		{SortCommitted, SortCheckout},
+		{SortCheckout, SortAuthor},
+		{SortAuthor, SortAheadBehind},
+		{SortAheadBehind, SortAlphabetical},
+		// Unknown modes start the cycle over.
+		{"unknown", SortAlphabetical},
+	}
+
+	for _, tt := range tests {
+		if got := nextSortMode(tt.mode); got != tt.want {
+			t.Errorf("nextSortMode(%q) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestSortBranches(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	branches := []git.Branch{
+		{Name: "beta", Ref: "refs/heads/beta", Author: "Charles", CommitterDate: day(3), Upstream: "origin/beta"},
+		{Name: "Alpha", Ref: "refs/heads/Alpha", Author: "ada", CommitterDate: day(1), Upstream: "origin/Alpha"},
+		{Name: "delta", Ref: "refs/heads/delta", Author: "Ada", CommitterDate: day(4)},
+		{Name: "gamma", Ref: "refs/heads/gamma", Author: "Charles", CommitterDate: day(3), Upstream: "origin/gamma"},
+		{Name: "epsilon", Ref: "refs/heads/epsilon", Author: "Ada", CommitterDate: day(2), Upstream: "origin/epsilon"},
+	}
+	counts := map[string]git.AheadBehind{
+		"beta":  {Ahead: 1, Behind: 5},
+		"Alpha": {Ahead: 1, Behind: 7},
+		"gamma": {Ahead: 4},
+		// epsilon's counts are still loading.
+	}
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{SortAlphabetical, []string{"Alpha", "beta", "delta", "epsilon", "gamma"}},
+		// Ties are broken by name.
+		{SortCommitted, []string{"delta", "beta", "gamma", "epsilon", "Alpha"}},
+		// Branches never checked out come last, most recently committed
+		// first.
+		{SortCheckout, []string{"gamma", "Alpha", "delta", "beta", "epsilon"}},
+		// Authors are compared ignoring case, then by commit date.
+		{SortAuthor, []string{"delta", "epsilon", "Alpha", "beta", "gamma"}},
+		// Branches without counts come last, most recently committed first.
+		{SortAheadBehind, []string{"gamma", "Alpha", "beta", "delta", "epsilon"}},
+		{"unknown", []string{"Alpha", "beta", "delta", "epsilon", "gamma"}},
+	}
+
+	for _, tt := range tests {
+		sorted := sortBranches(tt.mode, branches, []string{"gamma", "Alpha"}, func(b git.Branch) (git.AheadBehind, bool) {
+			c, ok := counts[b.Name]
+			return c, ok
+		})
+
+		got := lo.Map(sorted, func(b git.Branch, _ int) string {
+			return b.Name
+		})
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("sortBranches(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	if branches[0].Name != "beta" {
+		t.Error("sortBranches sorted the branches in place")
+	}
+}
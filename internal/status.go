@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+// statusSummary describes the changes in the working tree, such as
+// "2 staged, 1 unstaged".
+func statusSummary(status git.Status) string {
+	counts := []struct {
+		count int
+		label string
+	}{
+		{status.Conflicted, "conflicted"},
+		{status.Staged, "staged"},
+		{status.Unstaged, "unstaged"},
+		{status.Untracked, "untracked"},
+	}
+
+	parts := []string{}
+	for _, c := range counts {
+		if c.count > 0 {
+			parts = append(parts, fmt.Sprintf("%v %v", c.count, c.label))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "clean"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// confirmOperation calls next, unless an operation such as a rebase is in
+// progress and the named branch isn't the current branch, in which case the
+// user is warned and asked to confirm switching first.
+func (r *Renderer) confirmOperation(name string, next func()) {
+	if r.cfg.Status == nil || r.cfg.Status.Operation == "" || name == r.cfg.CurrentBranch {
+		next()
+		return
+	}
+
+	r.openPrompt(fmt.Sprintf("A %v is in progress. Switch anyway?", r.cfg.Status.Operation),
+		promptOption{key: 'y', label: "yes", run: func() error {
+			next()
+			return nil
+		}},
+	)
+}
@@ -1,12 +1,18 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/kirsle/configdir"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
 	"gopkg.in/yaml.v2"
 )
 
@@ -18,19 +24,73 @@ const (
 	StorageDirectory string = ".gitswitch"
 )
 
+// Directory is where the config is stored. Tests point it at a temporary
+// directory so that they don't touch the user's config.
+var Directory = configdir.LocalConfig(StorageDirectory)
+
 type RepositoryConfig struct {
 	Path           string   `yaml:"path"`
 	PinnedBranches []string `yaml:"pinned-branches"`
 	LastBranch     string   `yaml:"last-branch"`
+	History        []string `yaml:"history"`
+	// Include and Exclude are patterns selecting the branches listed for
+	// the repository, in addition to those in Config.
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	// HiddenBranches are branches the user chose to hide from the list.
+	HiddenBranches []string `yaml:"hidden-branches,omitempty"`
+	// AutoStash stashes uncommitted changes when switching away from a
+	// branch, and restores them when switching back to it.
+	AutoStash bool `yaml:"auto-stash,omitempty"`
+	// Stashes maps branches to the stash holding the changes stashed when
+	// switching away from them with AutoStash.
+	Stashes map[string]string `yaml:"stashes,omitempty"`
+	// TrustHooks runs the hooks set in the repository's committed config
+	// file, which are ignored otherwise.
+	TrustHooks bool `yaml:"trust-hooks,omitempty"`
+}
+
+// Hooks are shell commands run in the repository when switching branches,
+// with GIT_SWITCH_FROM set to the branch or commit switched from and
+// GIT_SWITCH_TO to the one switched to.
+type Hooks struct {
+	// PreSwitch is run before switching. The switch is cancelled if it
+	// fails.
+	PreSwitch string `yaml:"pre-switch,omitempty"`
+	// PostSwitch is run after switching.
+	PostSwitch string `yaml:"post-switch,omitempty"`
 }
 
 type Config struct {
-	Repositories        []RepositoryConfig `yaml:"repositories"`
+	Repositories        []RepositoryConfig `yaml:"repositories,omitempty"`
 	PinnedBranchPrefix  string             `yaml:"pinned-branch-prefix"`
+	PatternPinPrefix    string             `yaml:"pattern-pin-prefix"`
 	WindowSize          int                `yaml:"window-size"`
 	PruneRemoteBranches bool               `yaml:"prune-remote-branches"`
+	Fetch               bool               `yaml:"fetch"`
+	Columns             []string           `yaml:"columns"`
+	Sort                string             `yaml:"sort"`
+	Preview             bool               `yaml:"preview"`
+	HistoryDepth        int                `yaml:"history-depth"`
+	ReflogHistory       bool               `yaml:"reflog-history"`
+	Backend             string             `yaml:"backend"`
+	QuickSelect         string             `yaml:"quick-select"`
+	Hooks               Hooks              `yaml:"hooks"`
+	// Include and Exclude are patterns selecting the branches listed. When
+	// there are include patterns, only the branches matching one of them
+	// are listed. Branches matching an exclude pattern are never listed.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	// Sources lists the config files that were merged to produce the
+	// config, from lowest to highest precedence.
+	Sources []string `yaml:"-"`
 }
 
+// DefaultColumns are the columns displayed next to each branch when the
+// config doesn't specify any.
+var DefaultColumns = []string{"date", "ahead-behind", "author", "subject"}
+
 func (c *Config) GetRepositoryConfig(path string) (*RepositoryConfig, error) {
 	for _, rc := range c.Repositories {
 		if strings.EqualFold(rc.Path, path) {
@@ -44,77 +104,319 @@ func (c *Config) GetRepositoryConfig(path string) (*RepositoryConfig, error) {
 
 	c.Repositories = append(c.Repositories, rc)
 
-	return &rc, write(c)
-}
-
-func GetConfig() (*Config, error) {
-	storagePath := configdir.LocalConfig(StorageDirectory)
-	err := configdir.MakePath(storagePath) // Ensure it exists.
-	if err != nil {
-		return nil, err
-	}
+	// Add the repository to the config as it is now, rather than writing c,
+	// which may be missing changes made since it was read.
+	_, err := update(func(cfg *Config) error {
+		if !lo.ContainsBy(cfg.Repositories, func(r RepositoryConfig) bool {
+			return strings.EqualFold(r.Path, path)
+		}) {
+			cfg.Repositories = append(cfg.Repositories, rc)
+		}
+		return nil
+	})
 
-	configFile := filepath.Join(storagePath, "config")
+	return &rc, err
+}
 
-	cfg := Config{
+// defaultConfig returns the config used when there is no config file.
+func defaultConfig() Config {
+	return Config{
 		PinnedBranchPrefix:  "★",
+		PatternPinPrefix:    "☆",
 		Repositories:        []RepositoryConfig{},
 		WindowSize:          10,
 		PruneRemoteBranches: false,
+		Columns:             DefaultColumns,
+		Sort:                "alphabetical",
+		Preview:             true,
+		HistoryDepth:        10,
+		ReflogHistory:       true,
+		Backend:             "exec",
+		QuickSelect:         "alt",
 	}
+}
 
-	if _, err := os.Stat(configFile); err != nil {
-		if !os.IsNotExist(err) {
-			return nil, err
+// GetConfig returns the user's config. When repositoryPath is set, the
+// config files of the repository at that path provide the defaults for the
+// values the user's config doesn't set, see repositoryConfigFiles.
+func GetConfig(repositoryPath string) (*Config, error) {
+	var userData []byte
+	err := withLock(func() error {
+		var err error
+		userData, err = read()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The user's config can't fail to parse since read replaced it if it
+	// did.
+	var user Config
+	if err := yaml.Unmarshal(userData, &user); err != nil {
+		return nil, err
+	}
+
+	// Anyone who can push to the repository can change its committed config
+	// file, so its hooks are only run once the user trusts them.
+	trustHooks := lo.ContainsBy(user.Repositories, func(r RepositoryConfig) bool {
+		return strings.EqualFold(r.Path, repositoryPath) && r.TrustHooks
+	})
+
+	cfg := defaultConfig()
+
+	if repositoryPath != "" {
+		for _, file := range repositoryConfigFiles(repositoryPath) {
+			cfgData, err := os.ReadFile(file)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			// Pins and history are always kept in the user's config, and
+			// the backend is chosen before the repository is known.
+			repositories, backend, hooks := cfg.Repositories, cfg.Backend, cfg.Hooks
+			if err := merge(&cfg, cfgData); err != nil {
+				return nil, fmt.Errorf("%v: %w", file, err)
+			}
+			cfg.Repositories, cfg.Backend = repositories, backend
+			if file == filepath.Join(repositoryPath, RepositoryConfigFile) && !trustHooks {
+				cfg.Hooks = hooks
+			}
+
+			cfg.Sources = append(cfg.Sources, file)
 		}
+	}
 
-		cfgBytes, err := yaml.Marshal(cfg)
+	if err := merge(&cfg, userData); err != nil {
+		return nil, err
+	}
+	cfg.Sources = append(cfg.Sources, filepath.Join(Directory, "config"))
+
+	applyDefaults(&cfg)
+
+	return &cfg, nil
+}
+
+// merge sets the values in data on cfg, leaving the others as they are. The
+// include and exclude patterns are added to those of cfg rather than
+// replacing them.
+func merge(cfg *Config, data []byte) error {
+	include, exclude := cfg.Include, cfg.Exclude
+	cfg.Include, cfg.Exclude = nil, nil
+
+	err := yaml.Unmarshal(data, cfg)
+	cfg.Include = slices.Concat(include, cfg.Include)
+	cfg.Exclude = slices.Concat(exclude, cfg.Exclude)
+
+	return err
+}
+
+// update applies fn to the config and writes the result. The config can't
+// be changed by another process from the moment it is read until it has been
+// written, so that concurrent changes aren't lost. Nothing is written if fn
+// returns an error.
+func update(fn func(cfg *Config) error) (*Config, error) {
+	var cfg *Config
+	err := withLock(func() error {
+		var err error
+		cfg, err = load()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		err = os.WriteFile(configFile, cfgBytes, 0660)
-		if err != nil {
-			return nil, err
+		if err := fn(cfg); err != nil {
+			return err
 		}
 
-		return &cfg, nil
+		return write(cfg)
+	})
+
+	return cfg, err
+}
+
+// updateRepository updates the config of the repository backend is for with
+// fn, adding the repository to the config if needed. Nothing is written if fn
+// returns an error.
+func updateRepository(ctx context.Context, backend git.Backend, fn func(*RepositoryConfig) error) (*Config, error) {
+	repositoryPath, err := backend.GetRepositoryPath(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	cfgData, err := os.ReadFile(configFile)
+	return update(func(cfg *Config) error {
+		return fn(cfg.repository(repositoryPath))
+	})
+}
+
+// repository returns the config of the repository at path, adding it to c if
+// needed.
+func (c *Config) repository(path string) *RepositoryConfig {
+	_, idx, found := lo.FindIndexOf(c.Repositories, func(r RepositoryConfig) bool {
+		return r.Path == path
+	})
+	if !found {
+		c.Repositories = append(c.Repositories, RepositoryConfig{
+			Path:           path,
+			PinnedBranches: []string{},
+		})
+		idx = len(c.Repositories) - 1
+	}
+
+	return &c.Repositories[idx]
+}
+
+// load reads the user's config, with the defaults for the values it doesn't
+// set. The lock must be held.
+func load() (*Config, error) {
+	cfgData, err := read()
 	if err != nil {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(cfgData, &cfg)
+	cfg := defaultConfig()
+	if err := yaml.Unmarshal(cfgData, &cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+
+	return &cfg, nil
+}
+
+// read returns the content of the config file, which is empty if it doesn't
+// exist. If it can't be parsed, it is moved aside to a backup so that
+// git-switch keeps working with the default config. The lock must be held.
+func read() ([]byte, error) {
+	configFile := filepath.Join(Directory, "config")
+
+	cfgData, err := os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if err := yaml.Unmarshal(cfgData, &Config{}); err != nil {
+		backupFile := configFile + ".bak"
+		slog.Warn("config could not be parsed, replacing it with the default config",
+			slog.String("backup", backupFile),
+			slog.Any("error", err),
+		)
+
+		if err := os.Rename(configFile, backupFile); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	return cfgData, nil
+}
+
+// applyDefaults sets the values that must be set to their default when they
+// aren't.
+func applyDefaults(cfg *Config) {
 	if cfg.PinnedBranchPrefix == "" {
 		cfg.PinnedBranchPrefix = "★"
 	}
 
+	if cfg.PatternPinPrefix == "" {
+		cfg.PatternPinPrefix = "☆"
+	}
+
 	if cfg.WindowSize == 0 {
 		cfg.WindowSize = 10
 	}
 
-	return &cfg, nil
+	if cfg.HistoryDepth == 0 {
+		cfg.HistoryDepth = 10
+	}
+
+	if cfg.Sort == "" {
+		cfg.Sort = "alphabetical"
+	}
+
+	if cfg.Backend == "" {
+		cfg.Backend = "exec"
+	}
+
+	if cfg.QuickSelect == "" {
+		cfg.QuickSelect = "alt"
+	}
+
+	if cfg.Columns == nil {
+		cfg.Columns = DefaultColumns
+	}
 }
 
+// write writes the repositories of cfg to the config file. The other values
+// in the file are kept as the user wrote them, so that the defaults are never
+// written and the values the user didn't set can come from a repository's
+// config. The file is written to a temporary file and then renamed over the
+// config file, so that it is never left partially written. The lock must be
+// held.
 func write(cfg *Config) error {
-	storagePath := configdir.LocalConfig(StorageDirectory)
-	err := configdir.MakePath(storagePath) // Ensure it exists.
+	cfgData, err := read()
+	if err != nil {
+		return err
+	}
+
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(cfgData, &document); err != nil {
+		return err
+	}
+
+	document = slices.DeleteFunc(document, func(item yaml.MapItem) bool {
+		return item.Key == "repositories"
+	})
+	document = append(document, yaml.MapItem{Key: "repositories", Value: cfg.Repositories})
+
+	cfgBytes, err := yaml.Marshal(document)
 	if err != nil {
 		return err
 	}
 
-	cfgBytes, err := yaml.Marshal(cfg)
+	f, err := os.CreateTemp(Directory, "config-*.tmp")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(f.Name())
 
-	configFile := filepath.Join(storagePath, "config")
+	if _, err := f.Write(cfgBytes); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), 0660); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(Directory, "config"))
+}
+
+// Settings returns the config as YAML, leaving out the pins and history
+// stored for each repository.
+func (c *Config) Settings() (string, error) {
+	settings := *c
+	settings.Repositories = nil
+
+	cfgBytes, err := yaml.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
 
-	return os.WriteFile(configFile, cfgBytes, 0660)
+	return string(cfgBytes), nil
 }
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git/gittest"
+)
+
+func TestConcurrentPins(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo")
+
+	var (
+		wg   sync.WaitGroup
+		want []string
+	)
+	for i := range 20 {
+		branch := fmt.Sprintf("branch-%02d", i)
+		want = append(want, branch)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Pin(ctx, backend, branch); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := pinnedBranches(t, "/repo")
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("pinned branches = %v, want %v", got, want)
+	}
+}
+
+func TestGetConfigRecoversFromInvalidConfig(t *testing.T) {
+	useTempDirectory(t)
+
+	configFile := filepath.Join(Directory, "config")
+	invalid := []byte("repositories: [\n  - path: oops\n")
+	if err := os.WriteFile(configFile, invalid, 0660); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := GetConfig("")
+	if err != nil {
+		t.Fatalf("GetConfig with an invalid config: %v", err)
+	}
+
+	if cfg.WindowSize != 10 || len(cfg.Repositories) != 0 {
+		t.Errorf("GetConfig = %+v, want the default config", cfg)
+	}
+
+	backup, err := os.ReadFile(configFile + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != string(invalid) {
+		t.Errorf("backup = %q, want %q", backup, invalid)
+	}
+
+	// The invalid config is moved aside so that it only has to be recovered
+	// once.
+	if _, err := Pin(context.Background(), gittest.New("/repo"), "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pinnedBranches(t, "/repo"); !slices.Equal(got, []string{"main"}) {
+		t.Errorf("pinned branches = %v, want [main]", got)
+	}
+}
+
+func TestWriteLeavesNoTemporaryFiles(t *testing.T) {
+	useTempDirectory(t)
+
+	if _, err := Pin(context.Background(), gittest.New("/repo"), "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(Directory, "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
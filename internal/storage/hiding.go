@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+var (
+	ErrBranchNotHidden = errors.New("branch not hidden")
+)
+
+// Hide hides branch from the list of branches of the repository.
+func Hide(ctx context.Context, backend git.Backend, branch string) (*Config, error) {
+	branch = strings.TrimSpace(branch)
+
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		if !lo.Contains(rc.HiddenBranches, branch) {
+			rc.HiddenBranches = append(rc.HiddenBranches, branch)
+		}
+
+		return nil
+	})
+}
+
+// Unhide lists branch again after it was hidden with Hide. It returns
+// ErrBranchNotHidden if the branch wasn't hidden that way.
+func Unhide(ctx context.Context, backend git.Backend, branch string) (*Config, error) {
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		if !lo.Contains(rc.HiddenBranches, branch) {
+			return ErrBranchNotHidden
+		}
+
+		rc.HiddenBranches = lo.Without(rc.HiddenBranches, branch)
+		return nil
+	})
+}
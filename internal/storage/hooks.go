@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+// SetTrustHooks turns running the hooks set in the repository's committed
+// config file on or off.
+func SetTrustHooks(ctx context.Context, backend git.Backend, trusted bool) (*Config, error) {
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		rc.TrustHooks = trusted
+		return nil
+	})
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/kirsle/configdir"
+)
+
+// withLock runs fn while holding an exclusive advisory lock on the config,
+// waiting for any other git-switch process holding it to finish first.
+func withLock(fn func() error) error {
+	err := configdir.MakePath(Directory) // Ensure it exists.
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(Directory, "config.lock"), os.O_CREATE|os.O_RDWR, 0660)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	return fn()
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package storage
+
+import "os"
+
+// lockFile does nothing since locking isn't supported on this platform, so
+// concurrent changes to the config may be lost.
+func lockFile(*os.File) error {
+	return nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package storage
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRange is the number of bytes locked. Any range works as long as every
+// process locks the same one.
+const lockRange = 1
+
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, lockRange, 0, &windows.Overlapped{})
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, lockRange, 0, &windows.Overlapped{})
+}
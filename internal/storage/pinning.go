@@ -1,10 +1,14 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/nathan-fiscaletti/git-switch/internal/pattern"
 	"github.com/samber/lo"
 )
 
@@ -12,89 +16,80 @@ var (
 	ErrBranchNotPinned = errors.New("branch not pinned")
 )
 
-func Pin(branch string) (*Config, error) {
+// Pin pins branch, which can also be a pattern pinning every branch matching
+// it.
+func Pin(ctx context.Context, backend git.Backend, branch string) (*Config, error) {
 	branch = strings.TrimSpace(branch)
 
-	cfg, err := GetConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	repositoryPath, err := git.GetRepositoryPath()
-	if err != nil {
-		return nil, err
+	if pattern.IsPattern(branch) {
+		if _, err := pattern.Compile([]string{branch}); err != nil {
+			return nil, err
+		}
 	}
 
-	if _, idx, found := lo.FindIndexOf(cfg.Repositories, func(r RepositoryConfig) bool {
-		return r.Path == repositoryPath
-	}); found {
-		if !lo.Contains(cfg.Repositories[idx].PinnedBranches, branch) {
-			cfg.Repositories[idx].PinnedBranches = append(cfg.Repositories[idx].PinnedBranches, branch)
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		if !lo.Contains(rc.PinnedBranches, branch) {
+			rc.PinnedBranches = append(rc.PinnedBranches, branch)
 		}
-	} else {
-		cfg.Repositories = append(cfg.Repositories, RepositoryConfig{
-			Path:           repositoryPath,
-			PinnedBranches: []string{branch},
-		})
-	}
 
-	return cfg, write(cfg)
+		return nil
+	})
 }
 
-func Unpin(branch string) (*Config, error) {
-	cfg, err := GetConfig()
-	if err != nil {
-		return nil, err
-	}
+// PinAt pins branch at position in the pinned branches, 1 being the first,
+// moving it there if it's already pinned. A position past the end pins it
+// last.
+func PinAt(ctx context.Context, backend git.Backend, branch string, position int) (*Config, error) {
+	branch = strings.TrimSpace(branch)
 
-	repositoryPath, err := git.GetRepositoryPath()
-	if err != nil {
-		return nil, err
+	if position < 1 {
+		return nil, fmt.Errorf("invalid position: %v", position)
 	}
 
-	// Find the repository int he config.
-	_, i, found := lo.FindIndexOf(cfg.Repositories, func(r RepositoryConfig) bool {
-		return r.Path == repositoryPath
-	})
-	if !found {
-		return nil, ErrBranchNotPinned
+	if pattern.IsPattern(branch) {
+		if _, err := pattern.Compile([]string{branch}); err != nil {
+			return nil, err
+		}
 	}
 
-	// Find the pinned branch
-	_, j, found := lo.FindIndexOf(cfg.Repositories[i].PinnedBranches, func(f string) bool {
-		return f == branch
-	})
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		pinnedBranches := slices.DeleteFunc(slices.Clone(rc.PinnedBranches), func(b string) bool {
+			return b == branch
+		})
+		position = min(position-1, len(pinnedBranches))
+		rc.PinnedBranches = slices.Insert(pinnedBranches, position, branch)
 
-	if !found {
-		return nil, ErrBranchNotPinned
-	}
+		return nil
+	})
+}
 
-	// Remove the pinned branch using safe slice creation
-	pinnedBranches := cfg.Repositories[i].PinnedBranches
-	newPinnedBranches := make([]string, 0, len(pinnedBranches)-1)
-	newPinnedBranches = append(newPinnedBranches, pinnedBranches[:j]...)
-	newPinnedBranches = append(newPinnedBranches, pinnedBranches[j+1:]...)
-	cfg.Repositories[i].PinnedBranches = newPinnedBranches
+func Unpin(ctx context.Context, backend git.Backend, branch string) (*Config, error) {
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		// Find the pinned branch
+		_, j, found := lo.FindIndexOf(rc.PinnedBranches, func(f string) bool {
+			return f == branch
+		})
 
-	return cfg, write(cfg)
-}
+		if !found {
+			return ErrBranchNotPinned
+		}
 
-func ClearPins() error {
-	cfg, err := GetConfig()
-	if err != nil {
-		return err
-	}
+		// Remove the pinned branch using safe slice creation
+		pinnedBranches := rc.PinnedBranches
+		newPinnedBranches := make([]string, 0, len(pinnedBranches)-1)
+		newPinnedBranches = append(newPinnedBranches, pinnedBranches[:j]...)
+		newPinnedBranches = append(newPinnedBranches, pinnedBranches[j+1:]...)
+		rc.PinnedBranches = newPinnedBranches
 
-	repositoryPath, err := git.GetRepositoryPath()
-	if err != nil {
-		return err
-	}
+		return nil
+	})
+}
 
-	if _, idx, found := lo.FindIndexOf(cfg.Repositories, func(r RepositoryConfig) bool {
-		return r.Path == repositoryPath
-	}); found {
-		cfg.Repositories[idx].PinnedBranches = []string{}
-	}
+func ClearPins(ctx context.Context, backend git.Backend) error {
+	_, err := updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		rc.PinnedBranches = []string{}
+		return nil
+	})
 
-	return write(cfg)
+	return err
 }
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git/gittest"
+)
+
+// useTempDirectory stores the config in a temporary directory for the
+// duration of the test.
+func useTempDirectory(t *testing.T) {
+	t.Helper()
+
+	previous := Directory
+	Directory = t.TempDir()
+	t.Cleanup(func() { Directory = previous })
+}
+
+func pinnedBranches(t *testing.T, path string) []string {
+	t.Helper()
+
+	cfg, err := GetConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := cfg.GetRepositoryConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rc.PinnedBranches
+}
+
+func TestPin(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "feature")
+
+	for _, branch := range []string{"feature", " main ", "feature"} {
+		if _, err := Pin(ctx, backend, branch); err != nil {
+			t.Fatalf("Pin(%q): %v", branch, err)
+		}
+	}
+
+	want := []string{"feature", "main"}
+	if got := pinnedBranches(t, "/repo"); !slices.Equal(got, want) {
+		t.Errorf("pinned branches = %v, want %v", got, want)
+	}
+
+	if got := pinnedBranches(t, "/other"); len(got) != 0 {
+		t.Errorf("pinned branches of another repository = %v, want none", got)
+	}
+}
+
+func TestPinPattern(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main")
+
+	for _, p := range []string{"release/*", `re:^hotfix/\d+`} {
+		if _, err := Pin(ctx, backend, p); err != nil {
+			t.Fatalf("Pin(%q): %v", p, err)
+		}
+	}
+
+	if _, err := Pin(ctx, backend, "re:("); err == nil {
+		t.Error("pinning an invalid pattern succeeded")
+	}
+
+	want := []string{"release/*", `re:^hotfix/\d+`}
+	if got := pinnedBranches(t, "/repo"); !slices.Equal(got, want) {
+		t.Errorf("pinned branches = %v, want %v", got, want)
+	}
+}
+
+func TestUnpin(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "feature", "fix")
+
+	for _, branch := range []string{"main", "feature", "fix"} {
+		if _, err := Pin(ctx, backend, branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Unpin(ctx, backend, "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"main", "fix"}
+	if got := pinnedBranches(t, "/repo"); !slices.Equal(got, want) {
+		t.Errorf("pinned branches = %v, want %v", got, want)
+	}
+
+	if _, err := Unpin(ctx, backend, "feature"); !errors.Is(err, ErrBranchNotPinned) {
+		t.Errorf("Unpin of a branch that isn't pinned = %v, want %v", err, ErrBranchNotPinned)
+	}
+}
+
+func TestPinAt(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "feature", "fix")
+
+	steps := []struct {
+		branch   string
+		position int
+		want     []string
+	}{
+		{"feature", 1, []string{"feature"}},
+		{"fix", 5, []string{"feature", "fix"}},
+		{"main", 1, []string{"main", "feature", "fix"}},
+		{"feature", 3, []string{"main", "fix", "feature"}},
+		{"feature", 2, []string{"main", "feature", "fix"}},
+	}
+
+	for _, step := range steps {
+		if _, err := PinAt(ctx, backend, step.branch, step.position); err != nil {
+			t.Fatalf("PinAt(%q, %v): %v", step.branch, step.position, err)
+		}
+
+		if got := pinnedBranches(t, "/repo"); !slices.Equal(got, step.want) {
+			t.Errorf("pinned branches after PinAt(%q, %v) = %v, want %v", step.branch, step.position, got, step.want)
+		}
+	}
+
+	if _, err := PinAt(ctx, backend, "fix", 0); err == nil {
+		t.Error("pinning at position 0 succeeded")
+	}
+}
+
+func TestClearPins(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo", "main", "feature")
+
+	for _, branch := range []string{"main", "feature"} {
+		if _, err := Pin(ctx, backend, branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ClearPins(ctx, backend); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pinnedBranches(t, "/repo"); len(got) != 0 {
+		t.Errorf("pinned branches = %v, want none", got)
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepositoryConfigFile is the name of the config file a repository can have
+// to share settings, either at the root of the work tree to be committed, or
+// in the git directory to only apply to the local clone.
+const RepositoryConfigFile = ".gitswitch.yaml"
+
+// repositoryConfigFiles returns the paths the config files of the repository
+// at path can have, from lowest to highest precedence. The user's config takes
+// precedence over both.
+func repositoryConfigFiles(path string) []string {
+	files := []string{filepath.Join(path, RepositoryConfigFile)}
+	if gitDir := gitDirectory(path); gitDir != "" {
+		files = append(files, filepath.Join(gitDir, RepositoryConfigFile))
+	}
+
+	return files
+}
+
+// gitDirectory returns the git directory of the work tree at path, or an
+// empty string if it can't be found. The git directory of a linked worktree
+// is shared with the main work tree.
+func gitDirectory(path string) string {
+	dotGit := filepath.Join(path, ".git")
+
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return dotGit
+	}
+
+	// Linked worktrees have a .git file pointing at their own directory
+	// inside the main git directory, which in turn names the main git
+	// directory in its commondir file.
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return ""
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(path, gitDir)
+	}
+
+	commonDir, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+
+	dir := strings.TrimSpace(string(commonDir))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+
+	return dir
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git/gittest"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetConfigMergesRepositoryConfig(t *testing.T) {
+	useTempDirectory(t)
+	repo := t.TempDir()
+
+	writeFile(t, filepath.Join(Directory, "config"), "window-size: 5\npreview: false\nhistory-depth: 3\nexclude: [c]\n")
+	writeFile(t, filepath.Join(repo, ".gitswitch.yaml"), "window-size: 20\nsort: committed\ncolumns: [hash]\nexclude: [a]\nbackend: go-git\n")
+	writeFile(t, filepath.Join(repo, ".git", ".gitswitch.yaml"), "sort: checkout\nexclude: [b]\n")
+
+	cfg, err := GetConfig(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file in the git directory wins over the one in the work tree, and
+	// both only set the values the user's config doesn't.
+	if cfg.Sort != "checkout" {
+		t.Errorf("sort = %q, want %q", cfg.Sort, "checkout")
+	}
+	if !slices.Equal(cfg.Columns, []string{"hash"}) {
+		t.Errorf("columns = %v, want the repository's", cfg.Columns)
+	}
+	if cfg.WindowSize != 5 || cfg.Preview || cfg.HistoryDepth != 3 {
+		t.Errorf("window size = %v, preview = %v and history depth = %v, want the user's", cfg.WindowSize, cfg.Preview, cfg.HistoryDepth)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(cfg.Exclude, want) {
+		t.Errorf("exclude = %v, want %v", cfg.Exclude, want)
+	}
+	if cfg.Backend != "exec" {
+		t.Errorf("backend = %q, want it to only come from the user's config", cfg.Backend)
+	}
+
+	want := []string{
+		filepath.Join(repo, ".gitswitch.yaml"),
+		filepath.Join(repo, ".git", ".gitswitch.yaml"),
+		filepath.Join(Directory, "config"),
+	}
+	if !slices.Equal(cfg.Sources, want) {
+		t.Errorf("sources = %v, want %v", cfg.Sources, want)
+	}
+
+	// Only the user's config is used when no repository is given.
+	cfg, err = GetConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sort != "alphabetical" || len(cfg.Sources) != 1 {
+		t.Errorf("user config has sort %q from %v, want the default from the user's config only", cfg.Sort, cfg.Sources)
+	}
+}
+
+func TestRepositoryConfigIsNotStored(t *testing.T) {
+	useTempDirectory(t)
+	repo := t.TempDir()
+
+	writeFile(t, filepath.Join(repo, ".gitswitch.yaml"), "window-size: 20\nrepositories: [{path: elsewhere, pinned-branches: [x]}]\n")
+
+	cfg, err := GetConfig(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Repositories) != 0 {
+		t.Errorf("repositories = %v, want them to only come from the user's config", cfg.Repositories)
+	}
+
+	// Storing something doesn't copy the repository's settings into the
+	// user's config.
+	if _, err := Pin(context.Background(), gittest.New(repo), "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = GetConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WindowSize != 10 {
+		t.Errorf("user's window size = %v, want the default", cfg.WindowSize)
+	}
+
+	// Nor the defaults, which would then win over the repository's settings.
+	cfg, err = GetConfig(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WindowSize != 20 {
+		t.Errorf("window size = %v after storing a pin, want the repository's", cfg.WindowSize)
+	}
+}
+
+func TestGitDirectoryOfLinkedWorktree(t *testing.T) {
+	mainTree := t.TempDir()
+	worktree := t.TempDir()
+
+	gitDir := filepath.Join(mainTree, ".git", "worktrees", "feature")
+	writeFile(t, filepath.Join(gitDir, "commondir"), "../..\n")
+	writeFile(t, filepath.Join(worktree, ".git"), "gitdir: "+gitDir+"\n")
+
+	if got, want := gitDirectory(worktree), filepath.Join(mainTree, ".git"); got != want {
+		t.Errorf("gitDirectory = %q, want %q", got, want)
+	}
+}
+
+func TestGetConfigTrustsRepositoryHooks(t *testing.T) {
+	useTempDirectory(t)
+	repo := t.TempDir()
+
+	writeFile(t, filepath.Join(repo, ".gitswitch.yaml"), "hooks: {pre-switch: committed, post-switch: committed}\n")
+	writeFile(t, filepath.Join(repo, ".git", ".gitswitch.yaml"), "hooks: {post-switch: local}\n")
+
+	cfg, err := GetConfig(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Hooks{PostSwitch: "local"}); cfg.Hooks != want {
+		t.Errorf("hooks = %+v, want %+v without the committed ones", cfg.Hooks, want)
+	}
+
+	if _, err := SetTrustHooks(context.Background(), gittest.New(repo), true); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = GetConfig(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Hooks{PreSwitch: "committed", PostSwitch: "local"}); cfg.Hooks != want {
+		t.Errorf("hooks = %+v once trusted, want %+v", cfg.Hooks, want)
+	}
+}
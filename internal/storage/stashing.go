@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+// SetAutoStash turns auto-stashing on or off for the repository.
+func SetAutoStash(ctx context.Context, backend git.Backend, enabled bool) (*Config, error) {
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		rc.AutoStash = enabled
+		return nil
+	})
+}
+
+// SetStash records hash as the stash holding the changes stashed when
+// switching away from branch. An empty hash removes the record.
+func SetStash(ctx context.Context, backend git.Backend, branch, hash string) (*Config, error) {
+	return updateRepository(ctx, backend, func(rc *RepositoryConfig) error {
+		if hash == "" {
+			delete(rc.Stashes, branch)
+			return nil
+		}
+
+		if rc.Stashes == nil {
+			rc.Stashes = map[string]string{}
+		}
+		rc.Stashes[branch] = hash
+		return nil
+	})
+}
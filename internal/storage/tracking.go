@@ -1,36 +1,68 @@
 package storage
 
 import (
+	"context"
 	"strings"
 
 	"github.com/nathan-fiscaletti/git-switch/internal/git"
 	"github.com/samber/lo"
 )
 
-func SetLastBranch(branch string) (*Config, error) {
+// SetLastBranch records branch as the most recently visited branch, moving it
+// to the top of the repository's branch history.
+func SetLastBranch(ctx context.Context, backend git.Backend, branch string) (*Config, error) {
 	branch = strings.TrimSpace(branch)
 
-	cfg, err := GetConfig()
-	if err != nil {
-		return nil, err
+	if branch == "" {
+		return GetConfig("")
 	}
 
-	repositoryPath, err := git.GetRepositoryPath()
+	repositoryPath, err := backend.GetRepositoryPath(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if _, idx, found := lo.FindIndexOf(cfg.Repositories, func(r RepositoryConfig) bool {
-		return r.Path == repositoryPath
-	}); found {
-		cfg.Repositories[idx].LastBranch = branch
-	} else {
-		cfg.Repositories = append(cfg.Repositories, RepositoryConfig{
-			Path:           repositoryPath,
-			PinnedBranches: []string{},
-			LastBranch:     branch,
-		})
+	return update(func(cfg *Config) error {
+		rc := cfg.repository(repositoryPath)
+		rc.History = pushHistory(rc.BranchHistory(), branch, cfg.HistoryDepth)
+		rc.LastBranch = branch
+
+		return nil
+	})
+}
+
+// BranchHistory returns the branches visited in the repository, most recent
+// first.
+func (rc *RepositoryConfig) BranchHistory() []string {
+	// Configs written before the history was introduced only have the last
+	// branch.
+	if len(rc.History) == 0 && rc.LastBranch != "" {
+		return []string{rc.LastBranch}
+	}
+
+	return rc.History
+}
+
+// pushHistory moves branch to the front of history, dropping the oldest
+// entries so that history holds at most depth branches.
+func pushHistory(history []string, branch string, depth int) []string {
+	result := append([]string{branch}, lo.Without(history, branch)...)
+	if depth > 0 && len(result) > depth {
+		result = result[:depth]
+	}
+
+	return result
+}
+
+// MergeHistory reconciles the stored branch history with the branches visited
+// according to the reflog. The reflog records every checkout no matter how it
+// was made, so its order wins, with stored entries the reflog no longer knows
+// about (for example because it expired) appended after it.
+func MergeHistory(stored, reflog []string, depth int) []string {
+	result := lo.Uniq(append(append([]string{}, reflog...), stored...))
+	if depth > 0 && len(result) > depth {
+		result = result[:depth]
 	}
 
-	return cfg, write(cfg)
+	return result
 }
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/nathan-fiscaletti/git-switch/internal/git/gittest"
+)
+
+func TestSetLastBranch(t *testing.T) {
+	useTempDirectory(t)
+	ctx := context.Background()
+	backend := gittest.New("/repo")
+
+	for _, branch := range []string{"a", "b", "c", "a", ""} {
+		if _, err := SetLastBranch(ctx, backend, branch); err != nil {
+			t.Fatalf("SetLastBranch(%q): %v", branch, err)
+		}
+	}
+
+	cfg, err := GetConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := cfg.GetRepositoryConfig("/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a", "c", "b"}
+	if got := rc.BranchHistory(); !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+
+	if rc.LastBranch != "a" {
+		t.Errorf("last branch = %q, want %q", rc.LastBranch, "a")
+	}
+}
+
+func TestBranchHistoryMigratesLastBranch(t *testing.T) {
+	rc := RepositoryConfig{LastBranch: "main"}
+
+	want := []string{"main"}
+	if got := rc.BranchHistory(); !slices.Equal(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestPushHistory(t *testing.T) {
+	got := pushHistory([]string{"a", "b", "c"}, "c", 2)
+
+	want := []string{"c", "a"}
+	if !slices.Equal(got, want) {
+		t.Errorf("pushHistory = %v, want %v", got, want)
+	}
+}
+
+func TestMergeHistory(t *testing.T) {
+	got := MergeHistory([]string{"a", "b", "old"}, []string{"b", "c", "a"}, 0)
+
+	want := []string{"b", "c", "a", "old"}
+	if !slices.Equal(got, want) {
+		t.Errorf("MergeHistory = %v, want %v", got, want)
+	}
+
+	if got := MergeHistory([]string{"a", "b"}, []string{"c"}, 2); len(got) != 2 {
+		t.Errorf("MergeHistory with depth 2 = %v, want 2 branches", got)
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+)
+
+// items returns what the list is made of in the current mode: the tags in
+// tags mode, the commits in commits mode, the branches along with the
+// detached commits visited in history mode, or the branches.
+func (r *Renderer) items() []git.Branch {
+	switch r.state.Mode {
+	case modeTags:
+		return r.cfg.Tags
+	case modeCommits:
+		return r.state.Log.commits
+	case modeHistory:
+		return append(slices.Clip(r.cfg.Branches), r.historyCommits()...)
+	}
+
+	return r.cfg.Branches
+}
+
+// toggleTags switches between listing tags and listing branches.
+func (r *Renderer) toggleTags() {
+	if r.cfg.Tags == nil {
+		return
+	}
+
+	if r.state.Mode == modeTags {
+		r.state.Mode = modeBranches
+	} else {
+		r.state.Mode = modeTags
+	}
+	r.state.Selected = 0
+	r.state.WindowStart = 0
+}
+
+// selectDetached selects the named tag or commit to be checked out with HEAD
+// detached at it, asking what to do with uncommitted changes first.
+func (r *Renderer) selectDetached(handler SelectionHandler, name string) {
+	tag, _ := r.branch(name)
+
+	r.confirmOperation(name, func() {
+		r.confirmLocalChanges(name, func(stash bool) error {
+			r.state.Quit = true
+			if handler.OnSelect != nil {
+				handler.OnSelect(Selection{Branch: tag, Detach: true, Stash: stash})
+			}
+			return nil
+		})
+	})
+}
+
+// branchKeys are the keys acting on branches, which do nothing while tags are
+// listed.
+var branchKeys = []tcell.Key{
+	tcell.KeyCtrlD,
+	tcell.KeyCtrlU,
+	tcell.KeyCtrlX,
+	tcell.KeyCtrlN,
+	tcell.KeyCtrlW,
+	tcell.KeyCtrlK,
+	tcell.KeyCtrlT,
+}
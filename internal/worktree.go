@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"fmt"
+)
+
+// createWorktree asks the user to confirm creating a worktree for the named
+// branch, and selects the branch to be checked out in the new worktree.
+func (r *Renderer) createWorktree(handler SelectionHandler, name string) {
+	if name == r.cfg.CurrentBranch {
+		r.state.Message = fmt.Sprintf("%v is checked out here", name)
+		return
+	}
+
+	if path, ok := r.cfg.Worktrees[name]; ok {
+		r.state.Message = fmt.Sprintf("%v is already checked out in %v", name, path)
+		return
+	}
+
+	branch, _ := r.branch(name)
+	path := r.cfg.WorktreePath(name)
+
+	r.openPrompt(fmt.Sprintf("Create a worktree for %v at %v?", name, path),
+		promptOption{key: 'y', label: "yes", run: func() error {
+			r.chooseRemote(branch, func(remote string) error {
+				r.state.Quit = true
+				if handler.OnSelect != nil {
+					handler.OnSelect(Selection{
+						Branch:         branch,
+						Remote:         remote,
+						Worktree:       path,
+						CreateWorktree: true,
+					})
+				}
+				return nil
+			})
+			return nil
+		}},
+	)
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nathan-fiscaletti/git-switch/internal/app"
 	"github.com/nathan-fiscaletti/git-switch/internal/git"
@@ -12,10 +16,23 @@ import (
 	"github.com/nathan-fiscaletti/git-switch/pkg"
 )
 
+// backgroundTimeout limits how long git may take to load information shown in
+// the selector, such as the commit preview.
+const backgroundTimeout = 10 * time.Second
+
+// commitLimit is how many commits are listed when browsing the commits on a
+// branch.
+const commitLimit = 1000
+
 func main() {
+	ctx := context.Background()
+
 	var (
-		pipeOutput = false
-		pop        = false
+		pipeOutput  = false
+		pop         = false
+		popDepth    = 1
+		showHistory = false
+		goTo        = 0
 	)
 
 	if len(os.Args) > 1 {
@@ -30,28 +47,50 @@ func main() {
 				os.Exit(1)
 			}
 
-			inRepo, _ := git.IsGitRepository()
-			if !inRepo {
-				fmt.Printf("error: %v\n", "not a git repository")
-				os.Exit(1)
-			}
+			backend := openBackend(ctx)
 
 			switch args[0] {
 			case "pin":
 				args = args[1:]
 
+				// The position to pin the branch at, or 0 to pin it last
+				position := 0
+				for i := 0; i < len(args); i++ {
+					if args[i] != "--position" {
+						continue
+					}
+
+					if i+1 >= len(args) {
+						fmt.Println("error: --position requires a value")
+						os.Exit(1)
+					}
+
+					position, err = strconv.Atoi(args[i+1])
+					if err != nil || position < 1 {
+						fmt.Printf("error: invalid position: %v\n", args[i+1])
+						os.Exit(1)
+					}
+
+					args = append(args[:i], args[i+2:]...)
+					break
+				}
+
 				var branch string
 				if len(args) > 0 {
 					branch = args[0]
 				} else {
-					branch, err = git.GetCurrentBranch()
+					branch, err = backend.GetCurrentBranch(ctx)
 					if err != nil {
 						fmt.Printf("error: %v\n", err)
 						os.Exit(1)
 					}
 				}
 
-				_, err = storage.Pin(branch)
+				if position > 0 {
+					_, err = storage.PinAt(ctx, backend, branch, position)
+				} else {
+					_, err = storage.Pin(ctx, backend, branch)
+				}
 				if err != nil {
 					fmt.Printf("error: %v\n", err)
 					os.Exit(1)
@@ -64,7 +103,7 @@ func main() {
 				if len(args) > 0 {
 					branch = args[0]
 				} else {
-					branch, err = git.GetCurrentBranch()
+					branch, err = backend.GetCurrentBranch(ctx)
 					if err != nil {
 						fmt.Printf("error: %v\n", err)
 						os.Exit(1)
@@ -72,7 +111,7 @@ func main() {
 				}
 
 				if branch == "all" || branch == "*" {
-					err = storage.ClearPins()
+					err = storage.ClearPins(ctx, backend)
 					if err != nil {
 						fmt.Printf("error: %v\n", err)
 						os.Exit(1)
@@ -82,7 +121,7 @@ func main() {
 					os.Exit(0)
 				}
 
-				_, err = storage.Unpin(branch)
+				_, err = storage.Unpin(ctx, backend, branch)
 				if err != nil {
 					fmt.Printf("error: %v\n", err)
 					os.Exit(1)
@@ -94,6 +133,128 @@ func main() {
 				pipeOutput = true
 			case "pop":
 				pop = true
+				if len(args) > 1 {
+					popDepth, err = strconv.Atoi(args[1])
+					if err != nil || popDepth < 1 {
+						fmt.Printf("error: invalid pop depth: %v\n", args[1])
+						os.Exit(1)
+					}
+				}
+			case "history":
+				showHistory = true
+			case "go":
+				if len(args) < 2 {
+					fmt.Println("error: go requires the number of a pinned branch")
+					os.Exit(1)
+				}
+
+				goTo, err = strconv.Atoi(args[1])
+				if err != nil || goTo < 1 {
+					fmt.Printf("error: invalid pinned branch number: %v\n", args[1])
+					os.Exit(1)
+				}
+			case "autostash":
+				if len(args) > 1 {
+					if args[1] != "on" && args[1] != "off" {
+						fmt.Printf("error: autostash must be on or off: %v\n", args[1])
+						os.Exit(1)
+					}
+
+					_, err = storage.SetAutoStash(ctx, backend, args[1] == "on")
+					if err != nil {
+						fmt.Printf("error: %v\n", err)
+						os.Exit(1)
+					}
+				}
+
+				repositoryPath, err := backend.GetRepositoryPath(ctx)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				cfg, err := storage.GetConfig(repositoryPath)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				repository, err := cfg.GetRepositoryConfig(repositoryPath)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				if repository.AutoStash {
+					fmt.Println("autostash is on")
+				} else {
+					fmt.Println("autostash is off")
+				}
+				os.Exit(0)
+			case "hooks":
+				if len(args) > 1 {
+					if args[1] != "on" && args[1] != "off" {
+						fmt.Printf("error: hooks must be on or off: %v\n", args[1])
+						os.Exit(1)
+					}
+
+					_, err = storage.SetTrustHooks(ctx, backend, args[1] == "on")
+					if err != nil {
+						fmt.Printf("error: %v\n", err)
+						os.Exit(1)
+					}
+				}
+
+				repositoryPath, err := backend.GetRepositoryPath(ctx)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				cfg, err := storage.GetConfig(repositoryPath)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				repository, err := cfg.GetRepositoryConfig(repositoryPath)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				if repository.TrustHooks {
+					fmt.Printf("hooks in %v are run\n", storage.RepositoryConfigFile)
+				} else {
+					fmt.Printf("hooks in %v are not run\n", storage.RepositoryConfigFile)
+				}
+				os.Exit(0)
+			case "config":
+				repositoryPath, err := backend.GetRepositoryPath(ctx)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				cfg, err := storage.GetConfig(repositoryPath)
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				settings, err := cfg.Settings()
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
+					os.Exit(1)
+				}
+
+				// List the files the config was merged from, so it's clear
+				// where each value may have come from.
+				for _, source := range cfg.Sources {
+					fmt.Printf("# %v\n", source)
+				}
+				fmt.Print(settings)
+				os.Exit(0)
 			default:
 				fmt.Printf("unknown internal command: %v\n", args[0])
 				os.Exit(1)
@@ -114,15 +275,33 @@ func main() {
 			println()
 			println("Internal Commands:")
 			println()
-			println("  pin:   Pins the current branch")
-			println("  unpin: Unpins the current branch")
-			println("  pipe:  Pipes the selected branch name to stdout instead of checking it out")
-			println("  pop:   Checks out the last branch you were in.")
+			println("  pin:     Pins the current branch, or the branch or pattern passed. Pass --position N to pin it Nth")
+			println("  unpin:   Unpins the current branch")
+			println("  pipe:    Pipes the selected branch name to stdout instead of checking it out")
+			println("  pop:     Checks out the last branch or detached commit you were on. Pass N to go back N.")
+			println("  history: Lists the branches you have visited, most recent first.")
+			println("  go:      Checks out the pinned branch with the number passed, as numbered in the selector.")
+			println("  config:  Prints the config in effect for the current repository.")
+			println("  autostash: Prints whether changes are stashed per branch when switching. Pass on or off to change it.")
+			println("  hooks:   Prints whether the hooks in the repository's .gitswitch.yaml are run. Pass on or off to change it.")
 			println()
 			println("Interactive Mode Hotkeys:")
 			println()
 			println("  CTRL+D: Pin the selected branch")
 			println("  CTRL+U: Unpin the selected branch")
+			println("  SHIFT+UP/DOWN: Move the selected pinned branch up or down")
+			println("  ALT+1-9: Switch to the pinned branch with that number")
+			println("  CTRL+W: Create a worktree for the selected branch and print its path")
+			println("  CTRL+X: Delete the selected branch")
+			println("  CTRL+N: Create a branch named after the search from the selected branch")
+			println("  CTRL+K: Hide the selected branch, or unhide it")
+			println("  CTRL+T: Toggle showing hidden branches")
+			println("  CTRL+O: Cycle the sort mode")
+			println("  CTRL+R: Toggle the branch history")
+			println("  CTRL+G: Toggle listing tags, which are checked out detached")
+			println("  CTRL+L: Browse the commits on the selected branch, to check one out or branch from it")
+			println("  CTRL+P: Toggle the commit preview")
+			println("  PGUP/PGDN: Scroll the commit preview")
 			os.Exit(0)
 		case "--version":
 			fallthrough
@@ -141,25 +320,21 @@ func main() {
 				os.Exit(1)
 			}
 
-			inRepo, _ := git.IsGitRepository()
-			if !inRepo {
-				fmt.Printf("error: %v\n", "not a git repository")
-				os.Exit(1)
-			}
+			backend := openBackend(ctx)
 
-			currentBranch, err := git.GetCurrentBranch()
+			head, err := backend.GetHead(ctx)
 			if err != nil {
 				fmt.Printf("error: %v\n", err)
 				os.Exit(1)
 			}
 
-			_, err = storage.SetLastBranch(currentBranch)
+			_, err = storage.SetLastBranch(ctx, backend, app.HistoryEntry(head))
 			if err != nil {
 				fmt.Printf("error: %v\n", err)
 				os.Exit(1)
 			}
 
-			err = git.ExecuteCheckout(strings.Join(os.Args[1:], " "))
+			err = git.ExecuteCheckout(ctx, os.Args[1:])
 			if err != nil {
 				fmt.Printf("error: %v\n", err)
 				os.Exit(1)
@@ -174,66 +349,157 @@ func main() {
 		os.Exit(1)
 	}
 
-	inRepo, _ := git.IsGitRepository()
-	if !inRepo {
-		fmt.Printf("error: %v\n", "not a git repository")
+	backend := openBackend(ctx)
+
+	repositoryPath, err := backend.GetRepositoryPath(ctx)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := storage.GetConfig(repositoryPath)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
 
-	cfg, err := storage.GetConfig()
+	repository, err := cfg.GetRepositoryConfig(repositoryPath)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
 
-	repositoryPath, err := git.GetRepositoryPath()
+	head, err := backend.GetHead(ctx)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
+	currentBranch := head.Branch
 
-	repository, err := cfg.GetRepositoryConfig(repositoryPath)
+	branches, err := backend.ListBranches(ctx)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
 
-	currentBranch, err := git.GetCurrentBranch()
+	history, err := app.BranchHistory(ctx, backend, cfg, repository, app.HistoryEntry(head), branches)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
 
+	if showHistory {
+		if len(history) == 0 {
+			fmt.Println("no branch history")
+			os.Exit(0)
+		}
+
+		for i, branch := range history {
+			if git.IsFullHash(branch) {
+				branch = fmt.Sprintf("%v (detached)", branch[:7])
+			}
+			fmt.Printf("%3d  %v\n", i+1, branch)
+		}
+		os.Exit(0)
+	}
+
 	if pop {
-		if repository.LastBranch == "" {
-			fmt.Println("error: no branch to pop to")
+		_, err = app.Pop(ctx, backend, history, popDepth)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
 			os.Exit(1)
 		}
 
-		_, err = storage.SetLastBranch(currentBranch)
+		os.Exit(0)
+	}
+
+	if goTo > 0 {
+		_, err = app.Go(ctx, backend, repository.PinnedBranches, branches, goTo)
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 			os.Exit(1)
 		}
 
-		err = git.Checkout(repository.LastBranch)
+		os.Exit(0)
+	}
+
+	if cfg.Fetch || cfg.PruneRemoteBranches {
+		if cfg.Fetch {
+			err = git.Fetch(ctx, cfg.PruneRemoteBranches)
+		} else {
+			err = git.PruneRemoteBranches(ctx)
+		}
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 			os.Exit(1)
 		}
 
-		os.Exit(0)
+		branches, err = backend.ListBranches(ctx)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	remotes, err := backend.ListRemotes(ctx)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
 	}
 
-	if cfg.PruneRemoteBranches {
-		err := git.PruneRemoteBranches()
+	checkouts, err := backend.ListCheckouts(ctx)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Branches checked out in other worktrees can't be checked out here, so
+	// the user is offered to go to their worktree instead. The selector only
+	// prints branch names when piping, so worktrees are left out then.
+	var (
+		worktrees    map[string]string
+		worktreePath func(branch string) string
+	)
+	if !pipeOutput {
+		list, err := backend.ListWorktrees(ctx)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			os.Exit(1)
+		}
+
+		worktrees = app.OtherWorktrees(list, repositoryPath)
+		worktreePath = func(branch string) string {
+			return app.WorktreePath(repositoryPath, branch)
+		}
+	}
+
+	// Show the status of the working tree, and ask what to do with
+	// uncommitted changes before switching unless they are stashed
+	// automatically.
+	var status *git.Status
+	localChanges := false
+	if !pipeOutput {
+		s, err := backend.GetStatus(ctx)
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 			os.Exit(1)
 		}
+		status = &s
+		localChanges = !repository.AutoStash && s.HasLocalChanges()
+	}
+
+	tags, err := backend.ListTags(ctx)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var detachedHead string
+	if head.Detached() {
+		detachedHead = head.String()
 	}
 
-	branches, err := git.ListBranches()
+	hiddenBranches, err := app.HiddenBranches(cfg, repository, branches, currentBranch)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
@@ -241,49 +507,157 @@ func main() {
 
 	pinnedBranches := repository.PinnedBranches
 
+	// Background work done for the selector is cancelled once it closes, and
+	// is given a limited time so a slow git command can't pile up.
+	uiCtx, cancelUI := context.WithCancel(ctx)
+	withTimeout := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(uiCtx, backgroundTimeout)
+	}
+
 	branchSelector, err := pkg.NewBranchSelector(pkg.BranchSelectorArguments{
 		CurrentBranch:      currentBranch,
-		Branches:           branches,
+		DetachedHead:       detachedHead,
+		BranchDetails:      branches,
+		Columns:            cfg.Columns,
+		Sort:               cfg.Sort,
+		RecentlyCheckedOut: git.RecentlyCheckedOut(checkouts),
+		HidePreview:        !cfg.Preview,
+		AheadBehind: func(ref, base string) (pkg.AheadBehind, error) {
+			ctx, cancel := withTimeout()
+			defer cancel()
+			return git.CountAheadBehind(ctx, ref, base)
+		},
+		AllowCreateBranch: !pipeOutput,
+		Remotes:           remotes,
+		History:           history,
+		Preview: func(branch pkg.Branch) ([]string, error) {
+			// Show the commits on the branch that aren't on the current
+			// branch, or the latest commits for the current branch itself.
+			base := currentBranch
+			if branch.Local && branch.Name == currentBranch {
+				base = ""
+			}
+
+			ctx, cancel := withTimeout()
+			defer cancel()
+			return git.LogOneline(ctx, base, branch.Ref, 100)
+		},
 		WindowSize:         cfg.WindowSize,
 		SearchLabel:        "search branch",
 		PinnedBranches:     &pinnedBranches,
 		PinnedBranchPrefix: cfg.PinnedBranchPrefix,
+		PatternPinPrefix:   cfg.PatternPinPrefix,
+		QuickSelect:        cfg.QuickSelect,
 		OnPinBranch: func(branch string) error {
-			_, err := storage.Pin(branch)
+			_, err := storage.Pin(ctx, backend, branch)
 			return err
 		},
 		OnUnpinBranch: func(branch string) error {
-			_, err := storage.Unpin(branch)
+			_, err := storage.Unpin(ctx, backend, branch)
+			return err
+		},
+		OnMovePinnedBranch: func(branch string, position int) error {
+			_, err := storage.PinAt(ctx, backend, branch, position)
+			return err
+		},
+		HiddenBranches: hiddenBranches,
+		Tags:           tags,
+		Status:         status,
+		LocalChanges:   localChanges,
+		Worktrees:      worktrees,
+		WorktreePath:   worktreePath,
+		OnHideBranch: func(branch string) error {
+			_, err := storage.Hide(ctx, backend, branch)
+			return err
+		},
+		OnUnhideBranch: func(branch string) error {
+			_, err := storage.Unhide(ctx, backend, branch)
+			if errors.Is(err, storage.ErrBranchNotHidden) {
+				return fmt.Errorf("%v is hidden by an include or exclude pattern", branch)
+			}
 			return err
 		},
+		OnDeleteBranch: func(branch string, force bool) error {
+			return git.DeleteBranch(ctx, branch, force)
+		},
+		OnDeleteRemoteBranch: func(remote, branch string) error {
+			return git.DeleteRemoteBranch(ctx, remote, branch)
+		},
+		Commits: func(branch pkg.Branch) ([]pkg.Branch, error) {
+			ctx, cancel := withTimeout()
+			defer cancel()
+			return backend.ListCommits(ctx, branch.Ref, commitLimit)
+		},
 	})
 	if err != nil {
 		panic(err)
 	}
 
-	b, err := branchSelector.PickBranch()
+	selection, err := branchSelector.Pick()
+	cancelUI()
 	if err != nil {
 		panic(err)
 	}
 
+	b := selection.Branch.Name
+
 	if pipeOutput {
 		fmt.Println(b)
 		os.Exit(0)
 	}
 
+	// Print the path of the worktree so that a shell function can change to
+	// it, after creating it if needed.
+	if selection.Worktree != "" {
+		if selection.CreateWorktree {
+			err = backend.AddWorktree(ctx, selection.Worktree, selection.Remote, b)
+			if err != nil {
+				fmt.Printf("error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Println(selection.Worktree)
+		os.Exit(0)
+	}
+
 	if len(b) > 0 {
-		_, err = storage.SetLastBranch(currentBranch)
+		err = app.Switch(ctx, backend, selection)
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 			os.Exit(1)
 		}
 
-		err = git.Checkout(b)
-		if err != nil {
-			fmt.Printf("error: %v\n", err)
-			os.Exit(1)
+		if selection.Stash {
+			fmt.Println("Your changes were stashed, run `git stash pop` to restore them.")
+		}
+
+		if selection.PushRemote != "" {
+			err = git.PushSetUpstream(ctx, selection.PushRemote, b)
+			if err != nil {
+				fmt.Printf("error: %v\n", err)
+				os.Exit(1)
+			}
 		}
 	}
 
 	os.Exit(0)
 }
+
+// openBackend returns the git backend selected in the user's config for the
+// repository in the working directory.
+func openBackend(ctx context.Context) git.Backend {
+	cfg, err := storage.GetConfig("")
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	backend, err := git.NewBackend(ctx, cfg.Backend)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	return backend
+}
@@ -4,29 +4,193 @@ import (
 	"slices"
 	"sync"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/nathan-fiscaletti/git-switch/internal"
+	"github.com/nathan-fiscaletti/git-switch/internal/git"
+	"github.com/samber/lo"
+)
+
+// Branch describes a branch and the commit at its tip.
+type Branch = git.Branch
+
+// AheadBehind counts how far a branch has diverged from another.
+type AheadBehind = git.AheadBehind
+
+// Status describes the changes in the working tree and the operation in
+// progress, if any.
+type Status = git.Status
+
+// Operations that can be in progress in a working tree.
+const (
+	OperationMerge      = git.OperationMerge
+	OperationRebase     = git.OperationRebase
+	OperationCherryPick = git.OperationCherryPick
+	OperationRevert     = git.OperationRevert
+	OperationBisect     = git.OperationBisect
+)
+
+// Selection is the result of selecting a branch. When the selected branch
+// only exists on remotes, Remote holds the remote the user chose to track.
+// When the user chose to create a new branch, Create is set along with the
+// ref to start the branch from and the remote to push it to, if any.
+type Selection = internal.Selection
+
+// Columns that can be displayed next to each branch name.
+const (
+	ColumnDate     = internal.ColumnDate
+	ColumnAuthor   = internal.ColumnAuthor
+	ColumnHash     = internal.ColumnHash
+	ColumnUpstream = internal.ColumnUpstream
+	ColumnRemotes  = internal.ColumnRemotes
+	ColumnSubject  = internal.ColumnSubject
+
+	ColumnAheadBehind = internal.ColumnAheadBehind
+	ColumnDivergence  = internal.ColumnDivergence
+)
+
+// Quick select modes for numbered pinned branches.
+const (
+	QuickSelectAlt   = internal.QuickSelectAlt
+	QuickSelectDigit = internal.QuickSelectDigit
+)
+
+// Sort modes for the branch list.
+const (
+	SortAlphabetical = internal.SortAlphabetical
+	SortCommitted    = internal.SortCommitted
+	SortCheckout     = internal.SortCheckout
+	SortAuthor       = internal.SortAuthor
+	SortAheadBehind  = internal.SortAheadBehind
 )
 
 type BranchSelectorArguments struct {
 	// The current branch that is checked out.
 	CurrentBranch string
+	// DetachedHead describes the commit HEAD is detached at, such as its
+	// abbreviated hash and the nearest tag. It is shown in place of the
+	// current branch when CurrentBranch is empty.
+	DetachedHead string
 	// The list of branches to pick from.
 	Branches []string
+	// BranchDetails is the list of branches to pick from along with
+	// information about each branch. When set, it is used instead of
+	// Branches.
+	BranchDetails []Branch
+	// The columns to display next to each branch name, in order. Columns
+	// are only populated for branches passed in BranchDetails.
+	Columns []string
 	// The pinned branches to display these will always be displayed at the
 	// top of the list when able. Using a pointer allows the caller to
-	// manage the pinned branches state.
+	// manage the pinned branches state. Entries can also be glob patterns
+	// such as release/*, or regular expressions prefixed with re:, which
+	// pin every matching branch.
 	PinnedBranches *[]string
 	// PinnedBranchPrefix is the prefix to use before pinned branches.
 	PinnedBranchPrefix string
+	// PatternPinPrefix is the prefix to use before branches pinned by a
+	// pattern. PinnedBranchPrefix is used when it is empty.
+	PatternPinPrefix string
+	// QuickSelect chooses how the first nine pinned branches, which are
+	// numbered, are selected by their number: QuickSelectAlt selects them
+	// with Alt+N and QuickSelectDigit also by typing N while the search is
+	// empty. Defaults to QuickSelectAlt.
+	QuickSelect string
 	// The maximum number of branches to show at any given time.
 	WindowSize int
 	// The label to show in front of the search input.
 	SearchLabel string
+	// The initial sort mode for branches that aren't pinned. Defaults to
+	// SortAlphabetical. The user can cycle through sort modes at runtime.
+	Sort string
+	// The names of recently checked out branches, most recent first. Used
+	// when sorting by SortCheckout.
+	RecentlyCheckedOut []string
+	// Preview returns the lines to show in the preview pane for the
+	// highlighted branch, such as its recent commits. It is called in the
+	// background so it may be slow. The preview pane is disabled when
+	// Preview is nil.
+	Preview func(branch Branch) ([]string, error)
+	// AheadBehind counts the commits ref is ahead of and behind base. It is
+	// called in the background, and only for the branches currently visible,
+	// to populate the ColumnAheadBehind and ColumnDivergence columns.
+	AheadBehind func(ref, base string) (AheadBehind, error)
+	// AllowCreateBranch offers to create a branch named after the search
+	// input when no branch has that name. The selection returned by Pick
+	// has Create set when the user chooses to create a branch.
+	AllowCreateBranch bool
+	// Remotes lists the remotes the user can choose to push a newly created
+	// branch to.
+	Remotes []string
+	// History lists previously visited branches, most recent first, with
+	// the commits HEAD was detached at by their full hash. When set, the
+	// user can toggle a history mode that only lists these branches, where
+	// commits are checked out detached.
+	History []string
+	// Tags lists the tags the user can pick from in a tags mode, which they
+	// can toggle at runtime. The selection returned by Pick has Detach set
+	// when the user picks a tag. Tags mode is disabled when Tags is nil.
+	Tags []Branch
+	// Commits returns the commits on a branch or tag, most recent first, as
+	// branches named after their abbreviated hash with the full hash as
+	// their Ref and Hash. When set, the user can browse the commits on the
+	// highlighted branch and pick one. The selection returned by Pick then
+	// either has Detach set, or Create set with the commit as Base. It is
+	// called in the background when the user starts browsing.
+	Commits func(branch Branch) ([]Branch, error)
+	// HidePreview starts the selector with the preview pane hidden. The user
+	// can toggle it at runtime.
+	HidePreview bool
 	// Callback functions for pin/unpin operations
 	OnPinBranch   func(branch string) error
 	OnUnpinBranch func(branch string) error
+	// OnMovePinnedBranch is called when the user moves a pinned branch, or
+	// the pattern pinning it, to position in PinnedBranches, 1 being the
+	// first. PinnedBranches is reordered by the selector.
+	OnMovePinnedBranch func(branch string, position int) error
+	// OnDeleteBranch deletes a local branch, forcing the deletion if force
+	// is set. It should return ErrBranchNotMerged when the branch isn't
+	// fully merged, in which case the user is asked whether to force the
+	// deletion. Deleting is disabled when OnDeleteBranch is nil.
+	OnDeleteBranch func(branch string, force bool) error
+	// OnDeleteRemoteBranch deletes a branch from a remote. The user is
+	// offered to delete a branch from its remotes after deleting it locally,
+	// or when deleting a remote-only branch.
+	OnDeleteRemoteBranch func(remote, branch string) error
+	// HiddenBranches lists branches that aren't listed until the user
+	// chooses to show hidden branches. Pinned branches are always listed.
+	HiddenBranches []string
+	// OnHideBranch and OnUnhideBranch are called when the user hides a
+	// branch or lists a hidden branch again. Hiding is disabled when
+	// OnHideBranch is nil.
+	OnHideBranch   func(branch string) error
+	OnUnhideBranch func(branch string) error
+	// Status is shown next to the current branch. When an operation is in
+	// progress, the user is warned before switching branches.
+	Status *Status
+	// LocalChanges should be set when the working tree has uncommitted
+	// changes. Selecting a branch then asks whether to stash the changes,
+	// in which case the selection's Stash is set, or carry them to the
+	// branch. The changes are not stashed by the selector.
+	LocalChanges bool
+	// Worktrees maps branches checked out in other worktrees to the path of
+	// their worktree. They are marked in the list, and selecting one offers
+	// to go to its worktree, in which case the selection's Worktree is set.
+	Worktrees map[string]string
+	// WorktreePath returns the path to create a worktree for a branch at.
+	// When set, the user can choose to create a worktree for the selected
+	// branch, in which case the selection's Worktree and CreateWorktree are
+	// set. The worktree is not created by the selector.
+	WorktreePath func(branch string) string
+	// Screen is the screen to draw the selector on. The terminal is used
+	// when Screen is nil. Passing a tcell.SimulationScreen allows the
+	// selector to be driven without a terminal, for example in tests.
+	Screen tcell.Screen
 }
 
+// ErrBranchNotMerged should be returned by OnDeleteBranch when a branch
+// can't be deleted because it isn't fully merged.
+var ErrBranchNotMerged = git.ErrBranchNotMerged
+
 // Creates a new BranchSelector with the specified config
 func NewBranchSelector(cfg BranchSelectorArguments) (*BranchSelector, error) {
 	return &BranchSelector{cfg}, nil
@@ -38,23 +202,61 @@ type BranchSelector struct {
 
 // Present the branch selector to the user and return the selected branch.
 func (b *BranchSelector) PickBranch() (string, error) {
+	selection, err := b.Pick()
+	if err != nil {
+		return "", err
+	}
+
+	return selection.Branch.Name, nil
+}
+
+// Present the branch selector to the user and return the selection. The
+// selection is empty if the user exits without selecting a branch.
+func (b *BranchSelector) Pick() (Selection, error) {
+	branches := b.cfg.BranchDetails
+	if branches == nil {
+		branches = lo.Map(b.cfg.Branches, func(name string, _ int) Branch {
+			return Branch{Name: name, Local: true}
+		})
+	}
+
 	renderer, err := internal.NewRenderer(
 		internal.RendererConfig{
 			CurrentBranch:      b.cfg.CurrentBranch,
-			Branches:           b.cfg.Branches,
+			DetachedHead:       b.cfg.DetachedHead,
+			Branches:           branches,
+			Columns:            b.cfg.Columns,
+			Sort:               b.cfg.Sort,
+			RecentlyCheckedOut: b.cfg.RecentlyCheckedOut,
+			Preview:            b.cfg.Preview,
+			ShowPreview:        !b.cfg.HidePreview,
+			AheadBehind:        b.cfg.AheadBehind,
+			AllowCreate:        b.cfg.AllowCreateBranch,
+			Remotes:            b.cfg.Remotes,
+			History:            b.cfg.History,
+			HiddenBranches:     b.cfg.HiddenBranches,
+			Tags:               b.cfg.Tags,
+			Commits:            b.cfg.Commits,
+			Status:             b.cfg.Status,
+			LocalChanges:       b.cfg.LocalChanges,
+			Worktrees:          b.cfg.Worktrees,
+			WorktreePath:       b.cfg.WorktreePath,
 			PinnedBranches:     b.cfg.PinnedBranches,
 			WindowSize:         b.cfg.WindowSize,
 			SearchLabel:        b.cfg.SearchLabel,
 			PinnedBranchPrefix: b.cfg.PinnedBranchPrefix,
+			PatternPinPrefix:   b.cfg.PatternPinPrefix,
+			QuickSelect:        b.cfg.QuickSelect,
+			Screen:             b.cfg.Screen,
 		},
 	)
 	if err != nil {
-		return "", err
+		return Selection{}, err
 	}
 
 	var (
 		wg        sync.WaitGroup
-		result    string
+		result    Selection
 		resultErr error
 	)
 
@@ -69,7 +271,12 @@ func (b *BranchSelector) PickBranch() (string, error) {
 		// Run updates
 		for !renderer.IsDone() && resultErr == nil {
 			err := renderer.Run(internal.SelectionHandler{
-				OnSelect: func(v string) {
+				OnDelete:       b.cfg.OnDeleteBranch,
+				OnDeleteRemote: b.cfg.OnDeleteRemoteBranch,
+				OnHide:         b.cfg.OnHideBranch,
+				OnUnhide:       b.cfg.OnUnhideBranch,
+				OnMovePin:      b.cfg.OnMovePinnedBranch,
+				OnSelect: func(v Selection) {
 					result = v
 				},
 				OnPin: func(branch string) error {